@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/salfatigroup/nopeus/cli/util"
+	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/core"
+	"github.com/spf13/cobra"
+)
+
+// the kube context of the cluster the database runs in
+var kubeContext string
+
+func init() {
+	// define the db flags
+	dbRotateCredentialsCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file. Defaults to $( pwd )/nopeus.yaml")
+	dbRotateCredentialsCmd.Flags().StringVar(&kubeContext, "kube-context", "", "The kube context of the environment cluster. Defaults to the active context")
+
+	// register new commands
+	dbCmd.AddCommand(dbRotateCredentialsCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+// define the command grouping the database commands
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the databases of your environments",
+}
+
+// define the command that rotates the credentials of a database
+var dbRotateCredentialsCmd = &cobra.Command{
+	Use:   "rotate-credentials <database>",
+	Short: "Changes the database password and restarts the services using it",
+	Args:  cobra.ExactArgs(1),
+	Run:   dbRotateCredentials,
+}
+
+// This command rotates the generated credentials of a database
+func dbRotateCredentials(cmd *cobra.Command, args []string) {
+	dbName := args[0]
+	cfg := config.GetNopeusConfig()
+	if configPath != "" {
+		cfg.SetConfigPath(configPath)
+	}
+
+	// only the user configs are required to find the database
+	if err := cfg.Load(); err != nil {
+		terminate("db rotate-credentials", util.ErrCodeConfig, "failed to load nopeus config", err)
+	}
+
+	workloads, err := core.RotateDatabaseCredentials(context.Background(), cfg, dbName, kubeContext)
+	if err != nil {
+		terminate("db rotate-credentials", util.ErrCodeDatabase, "failed to rotate the database credentials", err)
+	}
+
+	restarted := []string{}
+	for _, workload := range workloads {
+		restarted = append(restarted, workload.String())
+	}
+	message := fmt.Sprintf("the credentials of database %s were rotated", dbName)
+	if len(restarted) > 0 {
+		message += ", restarted " + strings.Join(restarted, ", ")
+	}
+	util.Emit(&util.Event{
+		Type:    util.EventResult,
+		Command: "db rotate-credentials",
+		Service: dbName,
+		Status:  "success",
+		Message: message,
+	})
+}
@@ -1,8 +1,7 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/salfatigroup/gologsnag"
 	"github.com/salfatigroup/nopeus/cli/util"
@@ -10,6 +9,7 @@ import (
 	"github.com/salfatigroup/nopeus/core"
 	"github.com/salfatigroup/nopeus/logger"
 	"github.com/salfatigroup/nopeus/remote"
+	"github.com/salfatigroup/nopeus/tracing"
 	"github.com/spf13/cobra"
 )
 
@@ -47,39 +47,46 @@ var liftoffCmd = &cobra.Command{
 // This command parses the configuration file and
 // deploys the application to the cloud
 func liftoff(cmd *cobra.Command, args []string) {
-	fmt.Println(
-		"🔥",
-		util.GradientText("[NOPEUS::STARTUP]", "#db2777", "#f9a8d4"),
-		"- preparing your application for deployment to the cloud",
-	)
+	util.Emit(&util.Event{
+		Type:    util.EventPhaseStart,
+		Command: "liftoff",
+		Phase:   "startup",
+		Message: "preparing your application for deployment to the cloud",
+	})
 
 	// init configs
 	initConfig()
 	cfg := config.GetNopeusConfig()
 
+	// trace the liftoff phases if tracing is configured
+	if err := cfg.CAL.GetTracing().Apply(cmd.Context(), cfg.Runtime.ConfigPath); err != nil {
+		logger.Errorf("Failed to initialize tracing: %+v", err)
+		terminate("liftoff", util.ErrCodeConfig, "failed to initialize tracing", err)
+	}
+
 	// deploy the application
-	fmt.Println(
-		"🚀",
-		util.GradientText("[NOPEUS::LIFTOFF]", "#db2777", "#f9a8d4"),
-		"- deploying your application to the cloud",
-	)
-	if err := core.Deploy(cfg); err != nil {
+	util.Emit(&util.Event{
+		Type:    util.EventPhaseStart,
+		Command: "liftoff",
+		Phase:   "liftoff",
+		Message: "deploying your application to the cloud",
+	})
+	ctx, span := tracing.Start(cmd.Context(), "liftoff")
+	if err := core.Deploy(ctx, cfg); err != nil {
+		tracing.End(span, err)
 		logger.Publish(&gologsnag.PublishOptions{Event: "error", Description: err.Error(), Tags: &gologsnag.Tags{"func": "liftoff"}})
 		logger.Errorf("Failed to deploy application: %+v", err)
-		fmt.Println(
-			"💥",
-			util.GradientText("[NOPEUS::TERMINATE]", "#db2777", "#f9a8d4"),
-			" - failed to deploy your application to the cloud \n",
-			err,
-		)
-		os.Exit(1)
+		terminate("liftoff", util.ErrCodeDeploy, "failed to deploy your application to the cloud", err)
 	}
 
-	fmt.Println(
-		"🛰 ",
-		util.GradientText("[NOPEUS::MECO]", "#db2777", "#f9a8d4"),
-		"- your application is securely deployed to the cloud",
-	)
+	util.Emit(&util.Event{
+		Type:    util.EventResult,
+		Command: "liftoff",
+		Status:  "success",
+		Message: "your application is securely deployed to the cloud",
+	})
+	tracing.End(span, nil)
+	tracing.Shutdown(context.Background())
 	logger.Debug("Liftoff command finished")
 	logger.Publish(&gologsnag.PublishOptions{Event: "liftoff-finished", Icon: "🎉", Notify: true})
 }
@@ -103,13 +110,7 @@ func initConfig() {
 		if err != nil {
 			logger.Publish(&gologsnag.PublishOptions{Event: "error", Description: err.Error(), Tags: &gologsnag.Tags{"func": "initConfig"}})
 			logger.Errorf("Failed to create remote session: %+v", err)
-			fmt.Println(
-				"💥",
-				util.GradientText("[NOPEUS::TERMINATE]", "#db2777", "#f9a8d4"),
-				" - failed to create remote session \n",
-				err,
-			)
-			os.Exit(1)
+			terminate("liftoff", util.ErrCodeRemoteSession, "failed to create remote session", err)
 		}
 	}
 
@@ -117,13 +118,7 @@ func initConfig() {
 	if err := cfg.Init(); err != nil {
 		logger.Publish(&gologsnag.PublishOptions{Event: "error", Description: err.Error(), Tags: &gologsnag.Tags{"func": "initConfig"}})
 		logger.Errorf("Failed to initialize configs: %+v", err)
-		fmt.Println(
-			"💥",
-			util.GradientText("[NOPEUS::TERMINATE]", "#db2777", "#f9a8d4"),
-			"- failed to initialize nopeus config \n",
-			err,
-		)
-		os.Exit(1)
+		terminate("liftoff", util.ErrCodeConfig, "failed to initialize nopeus config", err)
 	}
 
 	logger.Publish(&gologsnag.PublishOptions{Event: "liftoff-config-initialized"})
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/salfatigroup/nopeus/cli/util"
+	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/core"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// define the plan flags
+	planCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file. Defaults to $( pwd )/nopeus.yaml")
+
+	// register new command
+	rootCmd.AddCommand(planCmd)
+}
+
+// define the command that shows the rollout order of the services
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Shows the order the services of each environment are deployed in",
+	Run:   plan,
+}
+
+// This command resolves the dependencies of the services
+// without connecting to the cloud
+func plan(cmd *cobra.Command, args []string) {
+	cfg := config.GetNopeusConfig()
+	if configPath != "" {
+		cfg.SetConfigPath(configPath)
+	}
+
+	// only the user configs are required to order the services
+	if err := cfg.Load(); err != nil {
+		terminate("plan", util.ErrCodeConfig, "failed to load nopeus config", err)
+	}
+
+	if err := core.Plan(cmd.Context(), cfg); err != nil {
+		terminate("plan", util.ErrCodeConfig, "failed to plan the rollout of your application", err)
+	}
+
+	util.Emit(&util.Event{
+		Type:    util.EventResult,
+		Command: "plan",
+		Status:  "success",
+		Message: "the services are deployed in the order above",
+	})
+}
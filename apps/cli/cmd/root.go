@@ -1,11 +1,12 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
 	"os"
 
 	"github.com/salfatigroup/nopeus/cli/util"
 	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/tracing"
 	"github.com/spf13/cobra"
 )
 
@@ -19,18 +20,44 @@ var rootCmd = &cobra.Command{
             cmd.Help()
         }
     },
+    PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+        cfg := config.GetNopeusConfig()
+
+        // define the output format of the events
+        if err := util.SetOutputFormat(outputFormat); err != nil {
+            return err
+        }
+
+        // define how the long running steps are reported
+        // json output never renders the live progress
+        if cfg.Runtime.Verbose {
+            util.SetProgressMode(util.ProgressModeVerbose)
+        } else if cfg.Runtime.Quiet || util.IsJSONOutput() {
+            util.SetProgressMode(util.ProgressModeQuiet)
+        }
+
+        return nil
+    },
     PersistentPostRun: func(cmd *cobra.Command, args []string) {
         cfg := config.GetNopeusConfig()
 
         // delete the tmp directory if RemoveKeepExecutionFiles is enabled
         if cfg.Runtime.RemoveKeepExecutionFiles {
             if err := os.RemoveAll(cfg.Runtime.TmpFileLocation); err != nil {
-                fmt.Printf("Error deleting tmp nopeus directory: %s\n", err)
+                util.Emit(&util.Event{
+                    Type:    util.EventError,
+                    Command: cmd.Name(),
+                    Message: "Error deleting tmp nopeus directory",
+                    Error:   err.Error(),
+                })
             }
         }
     },
 }
 
+// the output format as defined by the users flag
+var outputFormat string
+
 // add root command flags
 func init() {
     cfg := config.GetNopeusConfig()
@@ -38,6 +65,13 @@ func init() {
     // private command to keep the tmp directory (keep-execution-files)
     rootCmd.PersistentFlags().BoolVar(&cfg.Runtime.RemoveKeepExecutionFiles, "remote-keep-execution-files", false, "Keep the execution files in the tmp directory")
 
+    // output format of the cli
+    rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", util.OutputFormatText, "Output format - text or json (newline delimited json events)")
+
+    // progress output flags
+    rootCmd.PersistentFlags().BoolVar(&cfg.Runtime.Verbose, "verbose", false, "Print the raw terraform and helm logs")
+    rootCmd.PersistentFlags().BoolVarP(&cfg.Runtime.Quiet, "quiet", "q", false, "Print only a summary of each step, suitable for CI")
+
     // mark the flag as hidden
     rootCmd.Flags().MarkHidden("keep-execution-files")
 }
@@ -52,3 +86,25 @@ func Execute() error {
     return nil
 }
 
+
+// report a terminating error to the user and exit
+func terminate(command string, code string, message string, err error) {
+    util.Emit(&util.Event{
+        Type:    util.EventError,
+        Command: command,
+        Code:    code,
+        Message: message,
+        Error:   err.Error(),
+    })
+    util.Emit(&util.Event{
+        Type:    util.EventResult,
+        Command: command,
+        Status:  "failure",
+        Code:    code,
+        Error:   err.Error(),
+    })
+
+    // flush the pending spans before exiting
+    tracing.Shutdown(context.Background())
+    os.Exit(1)
+}
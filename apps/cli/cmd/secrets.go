@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/salfatigroup/nopeus/cli/util"
+	"github.com/salfatigroup/nopeus/config"
+	"github.com/spf13/cobra"
+)
+
+// the editor used when $EDITOR is not set
+const defaultEditor = "vi"
+
+func init() {
+	// define the secrets flags
+	secretsEditCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file. Defaults to $( pwd )/nopeus.yaml")
+
+	// register new commands
+	secretsCmd.AddCommand(secretsEditCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
+
+// define the command grouping the encrypted env files commands
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted env files of your environments",
+}
+
+// define the command that edits an encrypted env file
+var secretsEditCmd = &cobra.Command{
+	Use:   "edit <env>",
+	Short: "Decrypts the env file of the environment, opens it in $EDITOR and encrypts it back",
+	Args:  cobra.ExactArgs(1),
+	Run:   secretsEdit,
+}
+
+// This command edits the sops or age encrypted env file of an environment
+func secretsEdit(cmd *cobra.Command, args []string) {
+	envName := args[0]
+	cfg := config.GetNopeusConfig()
+	if configPath != "" {
+		cfg.SetConfigPath(configPath)
+	}
+
+	// only the user configs are required to find the env file
+	if err := cfg.Load(); err != nil {
+		terminate("secrets edit", util.ErrCodeConfig, "failed to load nopeus config", err)
+	}
+
+	envData, ok := cfg.CAL.GetEnvironments()[envName]
+	if !ok {
+		terminate("secrets edit", util.ErrCodeConfig, "unknown environment", fmt.Errorf("the environment %s is not defined in the nopeus config", envName))
+	}
+	if envData.GetEnvFileLocation() == "" {
+		terminate("secrets edit", util.ErrCodeConfig, "missing env file", fmt.Errorf("the environment %s has no env_file", envName))
+	}
+	file := filepath.Join(filepath.Dir(cfg.Runtime.ConfigPath), envData.GetEnvFileLocation())
+	recipients := ""
+	if envData.GetEnvFileRecipients() != "" {
+		recipients = filepath.Join(filepath.Dir(cfg.Runtime.ConfigPath), envData.GetEnvFileRecipients())
+	}
+
+	changed, err := config.EditEnvironmentFile(file, getEditor(), recipients)
+	if err != nil {
+		terminate("secrets edit", util.ErrCodeSecrets, "failed to edit the env file", err)
+	}
+
+	message := fmt.Sprintf("no changes were made to %s", file)
+	if changed {
+		message = fmt.Sprintf("%s was encrypted with your changes", file)
+	}
+	util.Emit(&util.Event{
+		Type:        util.EventResult,
+		Command:     "secrets edit",
+		Environment: envName,
+		Status:      "success",
+		Message:     message,
+	})
+}
+
+// return the editor command of the user, e.g., EDITOR="code --wait"
+func getEditor() string {
+	if editor := strings.TrimSpace(os.Getenv("EDITOR")); editor != "" {
+		return editor
+	}
+
+	return defaultEditor
+}
@@ -4,6 +4,7 @@ go 1.18
 
 require (
 	github.com/charmbracelet/lipgloss v0.5.0
+	github.com/mattn/go-isatty v0.0.14
 	github.com/salfatigroup/gologsnag v0.1.2
 	github.com/spf13/cobra v1.5.0
 	gopkg.in/go-playground/colors.v1 v1.2.0
@@ -12,7 +13,6 @@ require (
 require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-isatty v0.0.14 // indirect
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/muesli/reflow v0.2.1-0.20210115123740-9e1d0d53df68 // indirect
 	github.com/muesli/termenv v0.11.1-0.20220204035834-5ac8409525e0 // indirect
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"fmt"
+	"os"
 
 	"github.com/salfatigroup/nopeus/cli/cmd"
 )
@@ -9,7 +9,8 @@ import (
 // Nopeus adds an application layer to the cloud.
 // Simply define your applications and let nopeus do the rest.
 func main() {
+    // cobra already reports the error to the user
     if err := cmd.Execute(); err != nil {
-        fmt.Errorf("%v", err)
+        os.Exit(1)
     }
 }
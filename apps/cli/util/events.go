@@ -0,0 +1,220 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/salfatigroup/nopeus/logger"
+)
+
+// define the supported output formats
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
+// define the type of the events nopeus emits
+type EventType string
+
+const (
+	// a phase of the command has started
+	EventPhaseStart EventType = "phase_start"
+
+	// a phase of the command has finished
+	EventPhaseEnd EventType = "phase_end"
+
+	// a service helm release was applied to the cluster
+	EventServiceApplied EventType = "service_applied"
+
+	// a service was skipped because it is up to date
+	EventServiceSkipped EventType = "service_skipped"
+
+	// the changes terraform is going to apply
+	EventTerraformChanges EventType = "terraform_changes"
+
+	// the order the services are going to be applied in
+	EventRolloutPlan EventType = "rollout_plan"
+
+	// an informative message
+	EventMessage EventType = "message"
+
+	// a raw log line, only emitted in verbose mode
+	EventLog EventType = "log"
+
+	// a command has failed
+	EventError EventType = "error"
+
+	// the final result of a command
+	EventResult EventType = "result"
+)
+
+// define the error codes attached to error events
+const (
+	ErrCodeConfig        = "config_error"
+	ErrCodeRemoteSession = "remote_session_error"
+	ErrCodeDeploy        = "deploy_error"
+	ErrCodeSecrets       = "secrets_error"
+	ErrCodeDatabase      = "database_error"
+)
+
+// define a single event emitted by nopeus
+type Event struct {
+	Type        EventType         `json:"type"`
+	Time        time.Time         `json:"time"`
+	Command     string            `json:"command,omitempty"`
+	Phase       string            `json:"phase,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Service     string            `json:"service,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	Code        string            `json:"code,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	DurationMs  int64             `json:"duration_ms,omitempty"`
+	Changes     *TerraformChanges `json:"changes,omitempty"`
+	Batches     [][]string        `json:"batches,omitempty"`
+}
+
+// define the changes in a terraform plan
+type TerraformChanges struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// the interface used to render the event stream
+type EventHandler interface {
+	Handle(event *Event)
+}
+
+var (
+	// the handler that renders the event stream
+	eventHandler EventHandler = &humanEventHandler{}
+	outputFormat              = OutputFormatText
+	eventMutex   sync.Mutex
+)
+
+// define the output format of the cli
+func SetOutputFormat(format string) error {
+	switch format {
+	case OutputFormatText:
+		eventHandler = &humanEventHandler{}
+	case OutputFormatJSON:
+		eventHandler = &jsonEventHandler{out: os.Stdout}
+	default:
+		return fmt.Errorf("unsupported output format %s - use %s or %s", format, OutputFormatText, OutputFormatJSON)
+	}
+
+	outputFormat = format
+	return nil
+}
+
+// returns true if the cli outputs newline delimited json events
+func IsJSONOutput() bool {
+	return outputFormat == OutputFormatJSON
+}
+
+// emit a new event to the event stream
+func Emit(event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	// never print sensitive values, e.g., in error messages
+	event.Message = logger.Redact(event.Message)
+	event.Error = logger.Redact(event.Error)
+
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+	eventHandler.Handle(event)
+}
+
+// emit an informative message
+func Message(message string) {
+	Emit(&Event{Type: EventMessage, Message: message})
+}
+
+// render the events as newline delimited json
+type jsonEventHandler struct {
+	// the events are written to stdout, nothing else may write
+	// to it to keep the stream valid json
+	out io.Writer
+}
+
+func (h *jsonEventHandler) Handle(event *Event) {
+	if err := json.NewEncoder(h.out).Encode(event); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode event: %s\n", err)
+	}
+}
+
+// render the events for humans
+type humanEventHandler struct{}
+
+// the banners of the main liftoff phases
+var phaseBanners = map[string]struct {
+	icon  string
+	title string
+}{
+	"startup": {"🔥", "[NOPEUS::STARTUP]"},
+	"liftoff": {"🚀", "[NOPEUS::LIFTOFF]"},
+	"max-q":   {"🚀", "[NOPEUS::MAX-Q]"},
+}
+
+func (h *humanEventHandler) Handle(event *Event) {
+	switch event.Type {
+	case EventPhaseStart:
+		if banner, ok := phaseBanners[event.Phase]; ok {
+			title := banner.title
+			if event.Environment != "" {
+				title = strings.TrimSuffix(title, "]") + "::" + strings.ToUpper(event.Environment) + "]"
+			}
+			h.println(banner.icon, GradientText(title, "#db2777", "#f9a8d4"), "-", event.Message)
+		} else if event.Message != "" {
+			h.println(GrayText(event.Message))
+		}
+	case EventPhaseEnd, EventMessage, EventServiceApplied, EventServiceSkipped:
+		if event.Message != "" {
+			h.println(GrayText(event.Message))
+		}
+	case EventTerraformChanges:
+		if event.Changes != nil {
+			h.println(GrayText(fmt.Sprintf(
+				"Terraform plan: %d to add, %d to change, %d to destroy",
+				event.Changes.Add,
+				event.Changes.Change,
+				event.Changes.Destroy,
+			)))
+		}
+	case EventRolloutPlan:
+		h.println(GrayText("Rollout plan:"))
+		for i, batch := range event.Batches {
+			h.println(GrayText(fmt.Sprintf("  %d. %s", i+1, strings.Join(batch, ", "))))
+		}
+	case EventLog:
+		h.println(event.Message)
+	case EventError:
+		h.println("💥", GradientText("[NOPEUS::TERMINATE]", "#db2777", "#f9a8d4"), "-", event.Message, "\n", event.Error)
+	case EventResult:
+		if event.Status == "success" && event.Message != "" {
+			h.println("🛰 ", GradientText("[NOPEUS::MECO]", "#db2777", "#f9a8d4"), "-", event.Message)
+		}
+	}
+}
+
+// print a message through the active progress if one is rendered
+func (h *humanEventHandler) println(args ...interface{}) {
+	activeMutex.Lock()
+	p := activeProgress
+	activeMutex.Unlock()
+
+	if p != nil {
+		p.Println(args...)
+		return
+	}
+
+	fmt.Println(args...)
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+// render the events as json into the returned buffer until the test ends
+func captureJSONEvents(t *testing.T) *bytes.Buffer {
+	out := &bytes.Buffer{}
+	handler, format, mode := eventHandler, outputFormat, progressMode
+	t.Cleanup(func() {
+		eventHandler, outputFormat, progressMode = handler, format, mode
+	})
+
+	eventHandler = &jsonEventHandler{out: out}
+	outputFormat = OutputFormatJSON
+	progressMode = ProgressModeQuiet
+	return out
+}
+
+// decode every line of the event stream
+func decodeEvents(t *testing.T, out *bytes.Buffer) []*Event {
+	events := []*Event{}
+	decoder := json.NewDecoder(out)
+	for {
+		event := &Event{}
+		if err := decoder.Decode(event); err == io.EOF {
+			return events
+		} else if err != nil {
+			t.Fatalf("failed to decode the event stream %q: %v", out.String(), err)
+		}
+		events = append(events, event)
+	}
+}
+
+// TestSetOutputFormat rejects unknown formats
+func TestSetOutputFormat(t *testing.T) {
+	captureJSONEvents(t)
+
+	if err := SetOutputFormat("yaml"); err == nil {
+		t.Errorf("expected an error for the yaml format")
+	}
+	if err := SetOutputFormat(OutputFormatJSON); err != nil || !IsJSONOutput() {
+		t.Errorf("expected the json format to be set, got %v", err)
+	}
+}
+
+// TestEmitJSON writes a json line per event
+func TestEmitJSON(t *testing.T) {
+	out := captureJSONEvents(t)
+
+	Message("Applying helm chart for service api")
+	Emit(&Event{Type: EventRolloutPlan, Environment: "staging", Batches: [][]string{{"main"}, {"api", "web"}}})
+	Emit(&Event{Type: EventError, Command: "liftoff", Code: ErrCodeDeploy, Message: "failed to deploy", Error: "timeout"})
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %s", len(lines), out.String())
+	}
+
+	events := decodeEvents(t, out)
+	if events[0].Type != EventMessage || events[0].Message != "Applying helm chart for service api" || events[0].Time.IsZero() {
+		t.Errorf("unexpected message event %+v", events[0])
+	}
+	if events[1].Type != EventRolloutPlan || events[1].Environment != "staging" || len(events[1].Batches) != 2 || events[1].Batches[1][1] != "web" {
+		t.Errorf("unexpected rollout plan event %+v", events[1])
+	}
+	if events[2].Type != EventError || events[2].Code != ErrCodeDeploy || events[2].Error != "timeout" {
+		t.Errorf("unexpected error event %+v", events[2])
+	}
+}
+
+// TestProgressJSON reports the progress as phase events without rendering it
+func TestProgressJSON(t *testing.T) {
+	out := captureJSONEvents(t)
+
+	progress := NewProgress("terraform apply")
+	if _, err := progress.Write([]byte("aws_s3_bucket.main: Creating...\naws_s3_bucket.main: Creation complete after 2s\n")); err != nil {
+		t.Fatal(err)
+	}
+	progress.Done()
+
+	events := decodeEvents(t, out)
+	if len(events) != 2 {
+		t.Fatalf("expected a phase start and end, got %d events", len(events))
+	}
+	if events[0].Type != EventPhaseStart || events[0].Phase != "terraform apply" {
+		t.Errorf("unexpected start event %+v", events[0])
+	}
+	if events[1].Type != EventPhaseEnd || events[1].Phase != "terraform apply" || events[1].Message != "terraform apply finished in 00:00 - 1 created" {
+		t.Errorf("unexpected end event %+v", events[1])
+	}
+}
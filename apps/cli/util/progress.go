@@ -0,0 +1,322 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/salfatigroup/nopeus/logger"
+)
+
+// define how progress is reported to the user
+type ProgressMode int
+
+const (
+	// render a live status line with the current step
+	ProgressModeDefault ProgressMode = iota
+
+	// print the raw terraform and helm logs
+	ProgressModeVerbose
+
+	// print only a summary once each step is done, suitable for CI
+	ProgressModeQuiet
+)
+
+var (
+	// the global progress mode as defined by the user flags
+	progressMode = ProgressModeDefault
+
+	// the progress that is currently rendered
+	activeProgress *Progress
+	activeMutex    sync.Mutex
+)
+
+// match the terraform resource progress lines
+var (
+	tfStartedRegex  = regexp.MustCompile(`^(\S+): (Creating|Modifying|Destroying|Reading)\.\.\.`)
+	tfStillRegex    = regexp.MustCompile(`^(\S+): Still (creating|modifying|destroying|reading)\.\.\.`)
+	tfCreatedRegex  = regexp.MustCompile(`^(\S+): Creation complete`)
+	tfModifiedRegex = regexp.MustCompile(`^(\S+): Modifications complete`)
+	tfDestroyRegex  = regexp.MustCompile(`^(\S+): Destruction complete`)
+	ansiRegex       = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+)
+
+// set the global progress mode
+func SetProgressMode(mode ProgressMode) {
+	progressMode = mode
+}
+
+// return the global progress mode
+func GetProgressMode() ProgressMode {
+	return progressMode
+}
+
+// render the progress of a long running step - e.g., terraform apply.
+// progress implements io.Writer to consume the terraform output line by line
+type Progress struct {
+	mu          sync.Mutex
+	out         io.Writer
+	mode        ProgressMode
+	interactive bool
+	start       time.Time
+	step        string
+	current     string
+	created     int
+	modified    int
+	destroyed   int
+	buf         []byte
+	done        chan struct{}
+	stopped     bool
+}
+
+// create and render a new progress for the given step
+func NewProgress(step string) *Progress {
+	p := &Progress{
+		out:         os.Stdout,
+		mode:        progressMode,
+		interactive: isatty.IsTerminal(os.Stdout.Fd()),
+		start:       time.Now(),
+		step:        step,
+		done:        make(chan struct{}),
+	}
+
+	Emit(&Event{Type: EventPhaseStart, Phase: step})
+
+	activeMutex.Lock()
+	activeProgress = p
+	activeMutex.Unlock()
+
+	// refresh the elapsed time on interactive terminals
+	if p.mode == ProgressModeDefault && p.interactive {
+		go p.tick()
+	}
+
+	return p
+}
+
+// refresh the status line every second
+func (p *Progress) tick() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.render()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// update the current step
+func (p *Progress) SetStep(step string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.step = step
+	p.current = ""
+	p.render()
+}
+
+// consume the raw log output line by line
+func (p *Progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	logs := []string{}
+	p.buf = append(p.buf, b...)
+	for {
+		i := strings.IndexByte(string(p.buf), '\n')
+		if i < 0 {
+			break
+		}
+
+		line := string(p.buf[:i])
+		p.buf = p.buf[i+1:]
+		if log := p.line(line); log != "" {
+			logs = append(logs, log)
+		}
+	}
+	p.mu.Unlock()
+
+	p.emitLogs(logs...)
+	return len(b), nil
+}
+
+// consume a formatted log line - e.g., helm debug logs
+func (p *Progress) Logf(format string, args ...interface{}) {
+	p.mu.Lock()
+	log := p.line(fmt.Sprintf(format, args...))
+	p.mu.Unlock()
+
+	p.emitLogs(log)
+}
+
+// emit the raw log lines in verbose mode
+// must be called without holding the progress lock
+func (p *Progress) emitLogs(logs ...string) {
+	for _, log := range logs {
+		if log != "" {
+			Emit(&Event{Type: EventLog, Phase: p.step, Message: log})
+		}
+	}
+}
+
+// print a message without breaking the status line
+func (p *Progress) Println(args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	fmt.Fprintln(p.out, args...)
+	p.render()
+}
+
+// handle a single log line
+// returns the line if it should be emitted as a raw log
+func (p *Progress) line(line string) string {
+	line = logger.Redact(strings.TrimRight(ansiRegex.ReplaceAllString(line, ""), "\r"))
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+
+	if p.mode == ProgressModeVerbose {
+		return line
+	}
+
+	switch {
+	case tfCreatedRegex.MatchString(line):
+		p.created++
+		p.current = ""
+	case tfModifiedRegex.MatchString(line):
+		p.modified++
+		p.current = ""
+	case tfDestroyRegex.MatchString(line):
+		p.destroyed++
+		p.current = ""
+	case tfStartedRegex.MatchString(line):
+		match := tfStartedRegex.FindStringSubmatch(line)
+		p.current = strings.ToLower(match[2]) + " " + match[1]
+	case tfStillRegex.MatchString(line):
+		match := tfStillRegex.FindStringSubmatch(line)
+		p.current = match[2] + " " + match[1]
+	default:
+		// keep any other line (e.g., helm logs) as the current activity
+		p.current = line
+	}
+
+	// non interactive outputs print a line per finished resource
+	if !p.interactive && p.current == "" && p.mode == ProgressModeDefault {
+		fmt.Fprintln(p.out, GrayText(p.status()))
+		return ""
+	}
+
+	p.render()
+	return ""
+}
+
+// return the status line
+func (p *Progress) status() string {
+	elapsed := time.Since(p.start).Round(time.Second)
+	status := fmt.Sprintf("⏳ %s · %s", formatElapsed(elapsed), p.step)
+
+	if counts := p.counts(); counts != "" {
+		status += " · " + counts
+	}
+
+	if p.current != "" {
+		status += " · " + p.current
+	}
+
+	return status
+}
+
+// return the summary of the resources changes
+func (p *Progress) counts() string {
+	counts := []string{}
+	if p.created > 0 {
+		counts = append(counts, fmt.Sprintf("%d created", p.created))
+	}
+	if p.modified > 0 {
+		counts = append(counts, fmt.Sprintf("%d modified", p.modified))
+	}
+	if p.destroyed > 0 {
+		counts = append(counts, fmt.Sprintf("%d destroyed", p.destroyed))
+	}
+
+	return strings.Join(counts, ", ")
+}
+
+// render the status line on interactive terminals
+func (p *Progress) render() {
+	if p.stopped || p.mode != ProgressModeDefault || !p.interactive {
+		return
+	}
+
+	status := []rune(p.status())
+	// avoid wrapping lines on narrow terminals
+	if len(status) > 120 {
+		status = append(status[:117], []rune("...")...)
+	}
+
+	p.clear()
+	fmt.Fprint(p.out, GrayText(string(status)))
+}
+
+// clear the status line on interactive terminals
+func (p *Progress) clear() {
+	if p.stopped || p.mode != ProgressModeDefault || !p.interactive {
+		return
+	}
+
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+// stop rendering the progress and emit the summary
+func (p *Progress) Done() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+
+	// flush any partial line
+	log := ""
+	if len(p.buf) > 0 {
+		log = p.line(string(p.buf))
+		p.buf = nil
+	}
+
+	p.clear()
+	p.stopped = true
+	close(p.done)
+
+	elapsed := time.Since(p.start)
+	summary := fmt.Sprintf("%s finished in %s", p.step, formatElapsed(elapsed.Round(time.Second)))
+	if counts := p.counts(); counts != "" {
+		summary += " - " + counts
+	}
+	p.mu.Unlock()
+
+	activeMutex.Lock()
+	if activeProgress == p {
+		activeProgress = nil
+	}
+	activeMutex.Unlock()
+
+	p.emitLogs(log)
+	Emit(&Event{Type: EventPhaseEnd, Phase: p.step, Message: summary, DurationMs: elapsed.Milliseconds()})
+}
+
+// format the elapsed time as mm:ss
+func formatElapsed(elapsed time.Duration) string {
+	minutes := int(elapsed.Minutes())
+	seconds := int(elapsed.Seconds()) % 60
+	return fmt.Sprintf("%02d:%02d", minutes, seconds)
+}
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// create a progress rendering to the returned buffer
+func newTestProgress(mode ProgressMode, interactive bool) (*Progress, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &Progress{
+		out:         out,
+		mode:        mode,
+		interactive: interactive,
+		start:       time.Now(),
+		step:        "terraform apply",
+		done:        make(chan struct{}),
+	}, out
+}
+
+// the terraform output of a few resource changes
+const terraformApplyOutput = "aws_s3_bucket.main: Creating...\n" +
+	"aws_s3_bucket.main: Creation complete after 2s\n" +
+	"aws_iam_role.node: Modifying...\n" +
+	"aws_iam_role.node: Modifications complete after 1s\n" +
+	"aws_sqs_queue.old: Destroying...\n" +
+	"aws_sqs_queue.old: Destruction complete after 1s\n" +
+	"aws_eks_cluster.main: Still creating... [10s elapsed]\n"
+
+// TestProgressInteractive renders a status line with the counts and the current activity
+func TestProgressInteractive(t *testing.T) {
+	progress, out := newTestProgress(ProgressModeDefault, true)
+
+	// the lines are consumed even when written in chunks
+	for _, chunk := range []string{terraformApplyOutput[:20], terraformApplyOutput[20:]} {
+		if _, err := progress.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rendered := out.String()
+	if !strings.Contains(rendered, "\r\033[K") {
+		t.Errorf("expected the status line to be cleared before each render")
+	}
+	lines := strings.Split(rendered, "\r\033[K")
+	last := lines[len(lines)-1]
+	for _, expected := range []string{"terraform apply", "1 created, 1 modified, 1 destroyed", "creating aws_eks_cluster.main"} {
+		if !strings.Contains(last, expected) {
+			t.Errorf("expected the status line to contain %q, got %q", expected, last)
+		}
+	}
+}
+
+// TestProgressNonInteractive prints a line per finished resource instead of a status line
+func TestProgressNonInteractive(t *testing.T) {
+	progress, out := newTestProgress(ProgressModeDefault, false)
+	if _, err := progress.Write([]byte(terraformApplyOutput)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a line per finished resource, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "\r") {
+		t.Errorf("expected no status line on a non interactive output")
+	}
+	if !strings.Contains(lines[2], "1 created, 1 modified, 1 destroyed") {
+		t.Errorf("expected the counts in the last line, got %q", lines[2])
+	}
+}
+
+// TestProgressQuiet prints nothing until the step is done
+func TestProgressQuiet(t *testing.T) {
+	progress, out := newTestProgress(ProgressModeQuiet, true)
+	if _, err := progress.Write([]byte(terraformApplyOutput)); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("expected no output in quiet mode, got %q", out.String())
+	}
+}
+
+// TestProgressVerbose emits the raw lines as log events
+func TestProgressVerbose(t *testing.T) {
+	events := captureJSONEvents(t)
+	progress, out := newTestProgress(ProgressModeVerbose, true)
+
+	// the last line is flushed once the step is done
+	if _, err := progress.Write([]byte("\x1b[1mInitializing the backend...\x1b[0m\n\nTerraform has been successfully initialized!")); err != nil {
+		t.Fatal(err)
+	}
+	progress.Done()
+
+	if out.Len() != 0 {
+		t.Errorf("expected no status line in verbose mode, got %q", out.String())
+	}
+
+	decoded := decodeEvents(t, events)
+	logs := []string{}
+	for _, event := range decoded {
+		if event.Type == EventLog {
+			logs = append(logs, event.Message)
+		}
+	}
+	if strings.Join(logs, "|") != "Initializing the backend...|Terraform has been successfully initialized!" {
+		t.Errorf("expected the raw lines without colors, got %v", logs)
+	}
+	if last := decoded[len(decoded)-1]; last.Type != EventPhaseEnd {
+		t.Errorf("expected the step to end with a phase end event, got %+v", last)
+	}
+}
+
+// TestFormatElapsed formats the elapsed time as mm:ss
+func TestFormatElapsed(t *testing.T) {
+	for elapsed, expected := range map[time.Duration]string{
+		0:                               "00:00",
+		42 * time.Second:                "00:42",
+		12*time.Minute + 5*time.Second:  "12:05",
+		125*time.Minute + 1*time.Second: "125:01",
+	} {
+		if formatted := formatElapsed(elapsed); formatted != expected {
+			t.Errorf("expected %s, got %s", expected, formatted)
+		}
+	}
+}
@@ -27,10 +27,14 @@ func NewNopeusState(envName string, envData *config.EnvironmentConfig, cfg *conf
 		"terraform.tfstate",
 	)
 
-	// read the tfstate file
-	tfstate, err := readTfstate(tfstateLocation)
-	if err != nil {
-		return nil, err
+	// read the tfstate file unless terraform
+	// stores its state in a remote backend
+	tfstate := ""
+	if !cfg.CAL.GetTerraform().HasRemoteBackend() {
+		var err error
+		if tfstate, err = readTfstate(tfstateLocation); err != nil {
+			return nil, err
+		}
 	}
 
 	// get services
@@ -103,6 +107,12 @@ func (s *NopeusState) UnfoldNopeusState(cfg *config.NopeusConfig) error {
 		"terraform.tfstate",
 	)
 
+	// the state is managed by the terraform backend
+	// avoid writing a local state that terraform will try to migrate
+	if cfg.CAL.GetTerraform().HasRemoteBackend() || s.TerraformState == "" {
+		return nil
+	}
+
 	// write the tfstate to the tfstate location using ioutil
 	return os.WriteFile(tfstateLocation, []byte(s.TerraformState), 0o644)
 }
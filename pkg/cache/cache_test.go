@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/salfatigroup/nopeus/config"
+)
+
+// return a config with its terraform files in a temporary directory
+func newTestConfig(t *testing.T, terraform *config.TerraformConfig) *config.NopeusConfig {
+	runtime := config.NewRuntimeConfig()
+	runtime.TmpFileLocation = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(runtime.TmpFileLocation, "aws", "prod"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return &config.NopeusConfig{
+		Runtime: runtime,
+		CAL: &config.CloudApplicationLayerConfig{
+			Name:        "shop",
+			CloudVendor: "aws",
+			Services:    map[string]*config.Service{"api": {}},
+			Terraform:   terraform,
+		},
+	}
+}
+
+// TestNopeusStateTerraformState only embeds the tfstate when nopeus manages it
+func TestNopeusStateTerraformState(t *testing.T) {
+	for name, test := range map[string]struct {
+		terraform *config.TerraformConfig
+		embedded  string
+	}{
+		"nopeus state":   {terraform: nil, embedded: `{"version": 4}`},
+		"remote backend": {terraform: &config.TerraformConfig{Backend: &config.TerraformBackend{Type: "s3"}}, embedded: ""},
+	} {
+		cfg := newTestConfig(t, test.terraform)
+		tfstate := filepath.Join(cfg.Runtime.TmpFileLocation, "aws", "prod", "terraform.tfstate")
+		if err := os.WriteFile(tfstate, []byte(`{"version": 4}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		state, err := NewNopeusState("prod", &config.EnvironmentConfig{}, cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if state.TerraformState != test.embedded {
+			t.Errorf("%s: expected the embedded tfstate %q, got %q", name, test.embedded, state.TerraformState)
+		}
+
+		// unfolding a state must not leave a local tfstate for a remote backend
+		if err := os.Remove(tfstate); err != nil {
+			t.Fatal(err)
+		}
+		state.TerraformState = `{"version": 4}`
+		if err := state.UnfoldNopeusState(cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		_, err = os.Stat(tfstate)
+		if written := err == nil; written != (test.embedded != "") {
+			t.Errorf("%s: expected the tfstate to be written: %v, got %v", name, test.embedded != "", written)
+		}
+	}
+}
+
+// TestNopeusStateRemoteBackendWithoutTfstate doesn't require a local tfstate
+func TestNopeusStateRemoteBackendWithoutTfstate(t *testing.T) {
+	cfg := newTestConfig(t, &config.TerraformConfig{Backend: &config.TerraformBackend{Type: "s3"}})
+
+	if _, err := NewNopeusState("prod", &config.EnvironmentConfig{}, cfg); err != nil {
+		t.Errorf("expected the local tfstate to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// the cpu utilization the replicas are scaled on when no target is set
+const defaultTargetCPU = 80
+
+// define how the service replicas are scaled by a horizontal pod autoscaler
+type Autoscaling struct {
+	// the minimum amount of replicas, defaults to 1
+	MinReplicas int `yaml:"min_replicas"`
+
+	// the maximum amount of replicas
+	MaxReplicas int `yaml:"max_replicas"`
+
+	// the average cpu utilization to keep, in percent of the cpu request
+	TargetCPU int `yaml:"target_cpu"`
+
+	// the average memory utilization to keep, in percent of the memory request
+	TargetMemory int `yaml:"target_memory"`
+
+	// scale on a custom metric collected by the prometheus plugin
+	Metric *AutoscalingMetric `yaml:"metric"`
+}
+
+// define a custom per pod metric to scale on
+type AutoscalingMetric struct {
+	// the metric name as served by the prometheus adapter, e.g., http_requests_per_second
+	Name string `yaml:"name"`
+
+	// the average value per pod to keep, e.g., 100 or 500m
+	Target string `yaml:"target"`
+}
+
+// return the minimum amount of replicas, defaults to 1
+func (a *Autoscaling) GetMinReplicas() int {
+	if a.MinReplicas == 0 {
+		return 1
+	}
+
+	return a.MinReplicas
+}
+
+// return the target cpu utilization, defaults to 80
+// when the replicas are not scaled on any other target
+func (a *Autoscaling) GetTargetCPU() int {
+	if a.TargetCPU == 0 && a.TargetMemory == 0 && a.Metric == nil {
+		return defaultTargetCPU
+	}
+
+	return a.TargetCPU
+}
+
+// validate the autoscaling configs against the service resources
+// utilization targets are relative to the requested resources
+func (a *Autoscaling) Validate(resources *Resources) error {
+	if a == nil {
+		return nil
+	}
+
+	if a.MinReplicas < 0 {
+		return fmt.Errorf("invalid autoscaling min_replicas %d - expected a positive number", a.MinReplicas)
+	}
+	if a.MaxReplicas <= 0 {
+		return fmt.Errorf("autoscaling requires max_replicas")
+	}
+	if a.GetMinReplicas() > a.MaxReplicas {
+		return fmt.Errorf("autoscaling min_replicas %d is above max_replicas %d", a.GetMinReplicas(), a.MaxReplicas)
+	}
+
+	if a.TargetCPU < 0 || a.TargetMemory < 0 {
+		return fmt.Errorf("invalid autoscaling target - expected a positive utilization percentage")
+	}
+	if a.GetTargetCPU() > 0 && !resources.HasRequest("cpu") {
+		return fmt.Errorf("autoscaling on cpu requires resources.requests.cpu")
+	}
+	if a.TargetMemory > 0 && !resources.HasRequest("memory") {
+		return fmt.Errorf("autoscaling on memory requires resources.requests.memory")
+	}
+
+	if a.Metric != nil {
+		if a.Metric.Name == "" {
+			return fmt.Errorf("the autoscaling metric requires a name")
+		}
+		if _, err := resource.ParseQuantity(a.Metric.Target); err != nil {
+			return fmt.Errorf("invalid autoscaling metric target %s - expected a quantity, e.g., 100", a.Metric.Target)
+		}
+	}
+
+	return nil
+}
@@ -23,6 +23,23 @@ type CloudApplicationLayerConfig struct {
 
     // define the storage configs
     Storage *Storage `yaml:"storage"`
+
+    // define how the generated terraform modules are executed
+    Terraform *TerraformConfig `yaml:"terraform"`
+
+    // define where the usage telemetry is sent to
+    Telemetry *TelemetryConfig `yaml:"telemetry"`
+
+    // define where the liftoff traces are exported to
+    Tracing *TracingConfig `yaml:"tracing"`
+
+    // roll back the services to their previous release when
+    // the rollout fails - can be overridden per service
+    Atomic bool `yaml:"atomic"`
+
+    // the amount of independent services applied in parallel
+    // defaults to 4
+    Concurrency int `yaml:"concurrency"`
 }
 
 // create a new instance of the cloud application layer config
@@ -82,3 +99,32 @@ func (c *CloudApplicationLayerConfig) GetServices() (map[string]*Service, error)
 func (c *CloudApplicationLayerConfig) GetStorage() *Storage {
     return c.Storage
 }
+
+// return the terraform config
+func (c *CloudApplicationLayerConfig) GetTerraform() *TerraformConfig {
+    return c.Terraform
+}
+
+// return the telemetry configs
+func (c *CloudApplicationLayerConfig) GetTelemetry() *TelemetryConfig {
+    return c.Telemetry
+}
+
+// return the tracing configs
+func (c *CloudApplicationLayerConfig) GetTracing() *TracingConfig {
+    return c.Tracing
+}
+
+// return true if the releases should be rolled back on failure
+func (c *CloudApplicationLayerConfig) IsAtomic() bool {
+    return c.Atomic
+}
+
+// return the amount of services applied in parallel or the default concurrency
+func (c *CloudApplicationLayerConfig) GetConcurrency() int {
+    if c.Concurrency <= 0 {
+        return defaultConcurrency
+    }
+
+    return c.Concurrency
+}
@@ -61,5 +61,10 @@ func (c *NopeusConfig) parseConfig() error {
 		}
 	}
 
+	// configure the telemetry as soon as the user configs are known
+	if err := c.CAL.GetTelemetry().Apply(c.Runtime.ConfigPath); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// define the supported cronjob concurrency policies
+const (
+	// overlapping runs are allowed
+	ConcurrencyPolicyAllow = "allow"
+
+	// a run is skipped while the previous one is still running
+	ConcurrencyPolicyForbid = "forbid"
+
+	// a new run replaces the one that is still running
+	ConcurrencyPolicyReplace = "replace"
+)
+
+// the amount of finished runs kubernetes keeps by default
+const (
+	defaultSuccessfulJobsHistoryLimit = 3
+	defaultFailedJobsHistoryLimit     = 1
+)
+
+// the predefined schedules supported by kubernetes
+var cronScheduleMacros = map[string]bool{
+	"@yearly":   true,
+	"@annually": true,
+	"@monthly":  true,
+	"@weekly":   true,
+	"@daily":    true,
+	"@midnight": true,
+	"@hourly":   true,
+}
+
+// a single field of a cron schedule, e.g., *, 5, 1-5, */15 or mon,wed
+var cronFieldRegexp = regexp.MustCompile(`^(\*|\?|[0-9a-zA-Z]+(-[0-9a-zA-Z]+)?)(/[0-9]+)?(,(\*|[0-9a-zA-Z]+(-[0-9a-zA-Z]+)?)(/[0-9]+)?)*$`)
+
+// define the runtime cronjob template data - a process
+// that runs to completion on a cron schedule
+type NopeusCronJobMicroservice struct {
+	*NopeusDefaultMicroservice
+}
+
+// return the cronjob template data
+func NewCronJobTemplateData(cfg *NopeusConfig, name string, service *Service, env string) (ServiceTemplateData, error) {
+	if err := validateCronJob(service); err != nil {
+		return &NopeusCronJobMicroservice{}, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	microservice, err := newMicroservice(cfg, name, service, env)
+	if err != nil {
+		return &NopeusCronJobMicroservice{}, err
+	}
+
+	// the default chart renders a cronjob for the workload kind of the values
+	microservice.ValuesTemplate = "cronjob.values.yaml"
+	microservice.Values.Custom["Schedule"] = service.Schedule
+	microservice.Values.Custom["ConcurrencyPolicy"] = service.GetConcurrencyPolicy()
+	microservice.Values.Custom["SuccessfulJobsHistoryLimit"] = service.GetSuccessfulJobsHistoryLimit()
+	microservice.Values.Custom["FailedJobsHistoryLimit"] = service.GetFailedJobsHistoryLimit()
+
+	return &NopeusCronJobMicroservice{NopeusDefaultMicroservice: microservice}, nil
+}
+
+// validate the configs of a cronjob
+func validateCronJob(service *Service) error {
+	if err := validateCronSchedule(service.Schedule); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(service.ConcurrencyPolicy) {
+	case "", ConcurrencyPolicyAllow, ConcurrencyPolicyForbid, ConcurrencyPolicyReplace:
+	default:
+		return fmt.Errorf("invalid concurrency_policy %s - expected %s, %s or %s", service.ConcurrencyPolicy, ConcurrencyPolicyAllow, ConcurrencyPolicyForbid, ConcurrencyPolicyReplace)
+	}
+
+	if limit := service.SuccessfulJobsHistoryLimit; limit != nil && *limit < 0 {
+		return fmt.Errorf("invalid successful_jobs_history_limit %d - expected a positive number", *limit)
+	}
+	if limit := service.FailedJobsHistoryLimit; limit != nil && *limit < 0 {
+		return fmt.Errorf("invalid failed_jobs_history_limit %d - expected a positive number", *limit)
+	}
+
+	// a cronjob runs to completion and never receives traffic
+	if service.Ingress != nil {
+		return fmt.Errorf("a %s can't have an ingress", ServiceKindCronJob)
+	}
+	if service.HealthCheckURL != "" {
+		return fmt.Errorf("a %s can't have a health_url", ServiceKindCronJob)
+	}
+	if len(service.Ports) > 0 {
+		return fmt.Errorf("a %s can't have ports", ServiceKindCronJob)
+	}
+	if service.Probes != nil {
+		return fmt.Errorf("a %s can't have probes", ServiceKindCronJob)
+	}
+	if service.Replicas != 0 {
+		return fmt.Errorf("a %s can't have replicas", ServiceKindCronJob)
+	}
+	if service.Autoscaling != nil {
+		return fmt.Errorf("a %s can't have autoscaling", ServiceKindCronJob)
+	}
+	if service.Strategy != nil {
+		return fmt.Errorf("a %s can't have a rollout strategy", ServiceKindCronJob)
+	}
+
+	return nil
+}
+
+// validate the given schedule is a standard five fields
+// cron expression or one of the predefined schedules
+func validateCronSchedule(schedule string) error {
+	if schedule == "" {
+		return fmt.Errorf("a %s requires a schedule, e.g., \"*/5 * * * *\"", ServiceKindCronJob)
+	}
+
+	if strings.HasPrefix(schedule, "@") {
+		if !cronScheduleMacros[schedule] {
+			return fmt.Errorf("invalid schedule %s - unknown predefined schedule", schedule)
+		}
+		return nil
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return fmt.Errorf("invalid schedule %s - expected 5 fields (minute hour day-of-month month day-of-week)", schedule)
+	}
+	for _, field := range fields {
+		if !cronFieldRegexp.MatchString(field) {
+			return fmt.Errorf("invalid schedule %s - unexpected field %s", schedule, field)
+		}
+	}
+
+	return nil
+}
+
+// return the kubernetes concurrency policy of the cronjob, defaults to Allow
+func (s *Service) GetConcurrencyPolicy() string {
+	switch strings.ToLower(s.ConcurrencyPolicy) {
+	case ConcurrencyPolicyForbid:
+		return "Forbid"
+	case ConcurrencyPolicyReplace:
+		return "Replace"
+	default:
+		return "Allow"
+	}
+}
+
+// return the amount of successful runs to keep
+func (s *Service) GetSuccessfulJobsHistoryLimit() int {
+	if s.SuccessfulJobsHistoryLimit == nil {
+		return defaultSuccessfulJobsHistoryLimit
+	}
+
+	return *s.SuccessfulJobsHistoryLimit
+}
+
+// return the amount of failed runs to keep
+func (s *Service) GetFailedJobsHistoryLimit() int {
+	if s.FailedJobsHistoryLimit == nil {
+		return defaultFailedJobsHistoryLimit
+	}
+
+	return *s.FailedJobsHistoryLimit
+}
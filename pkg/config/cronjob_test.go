@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+// TestValidateCronSchedule accepts standard and predefined schedules
+func TestValidateCronSchedule(t *testing.T) {
+	valid := []string{"*/5 * * * *", "0 3 * * mon-fri", "15,45 9-17 1 */2 *", "@daily"}
+	for _, schedule := range valid {
+		if err := validateCronSchedule(schedule); err != nil {
+			t.Errorf("expected %q to be valid, got %v", schedule, err)
+		}
+	}
+
+	invalid := []string{"", "* * * *", "* * * * * *", "@often", "*/x * * * *", "5; * * * *"}
+	for _, schedule := range invalid {
+		if err := validateCronSchedule(schedule); err == nil {
+			t.Errorf("expected %q to be invalid", schedule)
+		}
+	}
+}
+
+// TestValidateCronJob rejects the configs that only apply to long running services
+func TestValidateCronJob(t *testing.T) {
+	limit := -1
+	cases := map[string]*Service{
+		"missing schedule":   {},
+		"invalid policy":     {Schedule: "@hourly", ConcurrencyPolicy: "sometimes"},
+		"negative limit":     {Schedule: "@hourly", FailedJobsHistoryLimit: &limit},
+		"ingress":            {Schedule: "@hourly", Ingress: &Ingress{}},
+		"health url":         {Schedule: "@hourly", HealthCheckURL: "/status"},
+		"replicas":           {Schedule: "@hourly", Replicas: 2},
+		"autoscaling":        {Schedule: "@hourly", Autoscaling: &Autoscaling{MaxReplicas: 2}},
+		"probes":             {Schedule: "@hourly", Probes: &Probes{}},
+		"ports":              {Schedule: "@hourly", Ports: []*Port{{Name: "http", Port: 80}}},
+		"rollout strategies": {Schedule: "@hourly", Strategy: &Strategy{Type: StrategyRolling}},
+	}
+	for name, service := range cases {
+		if err := validateCronJob(service); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	service := &Service{Schedule: "0 * * * *", ConcurrencyPolicy: "Forbid"}
+	if err := validateCronJob(service); err != nil {
+		t.Fatal(err)
+	}
+	if service.GetConcurrencyPolicy() != "Forbid" {
+		t.Errorf("expected the Forbid policy, got %s", service.GetConcurrencyPolicy())
+	}
+	if service.GetSuccessfulJobsHistoryLimit() != 3 || service.GetFailedJobsHistoryLimit() != 1 {
+		t.Errorf("expected the default history limits")
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/salfatigroup/nopeus/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// the keys of the database secret, as read by the database chart
+const (
+	// the password of the database user
+	databasePasswordKey = "password"
+
+	// the password of the postgres superuser
+	databasePostgresPasswordKey = "postgres-password"
+
+	// the password of the replication manager
+	databaseRepmgrPasswordKey = "repmgr-password"
+
+	// the password of the connection pooler admin
+	databaseAdminPasswordKey = "admin-password"
+
+	// the rotated password of the database user until it is applied
+	databaseNextPasswordKey = "password-next"
+)
+
+// the length of the generated passwords
+const databasePasswordLength = 32
+
+// only alphanumeric characters so the passwords are safe in connection strings
+const databasePasswordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// the image of the job changing the database password
+const databaseRotationImage = "postgres:15-alpine"
+
+// change the password of the database user to the one in NEW_PASSWORD
+// the password is passed as a psql variable to quote it safely
+const databaseRotationScript = `echo "ALTER USER ` + databaseUser + ` WITH PASSWORD :'password';" | psql -v ON_ERROR_STOP=1 -v password="$NEW_PASSWORD"`
+
+// return the name of the secret holding the credentials of a database
+func databaseSecretName(name string) string {
+	return "database-secrets-" + name
+}
+
+// return the labels of the secret of the database
+func databaseSecretLabels(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by":  "nopeus",
+		"nopeus.salfati.group/database": name,
+	}
+}
+
+// return a random alphanumeric password
+func generatePassword() (string, error) {
+	password := make([]byte, databasePasswordLength)
+	max := big.NewInt(int64(len(databasePasswordAlphabet)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = databasePasswordAlphabet[n.Int64()]
+	}
+
+	return string(password), nil
+}
+
+// create the secret of the database with random credentials unless it
+// already exists, so the credentials are kept across deployments.
+// returns true if the credentials were generated
+func EnsureDatabaseCredentials(ctx context.Context, client k8s.Interface, namespace string, name string) (bool, error) {
+	if err := kubernetes.EnsureNamespace(ctx, client, namespace); err != nil {
+		return false, err
+	}
+
+	data := map[string]string{}
+	for _, key := range []string{databasePasswordKey, databasePostgresPasswordKey, databaseRepmgrPasswordKey, databaseAdminPasswordKey} {
+		password, err := generatePassword()
+		if err != nil {
+			return false, err
+		}
+		data[key] = password
+	}
+
+	return kubernetes.CreateSecretIfMissing(ctx, client, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: databaseSecretName(name), Namespace: namespace, Labels: databaseSecretLabels(name)},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: data,
+	})
+}
+
+// change the password of the database user and restart the workloads
+// reading it. the new password is staged in the secret and applied by a job
+// connecting with the current one, so the secret only changes once the
+// database accepted the new password. returns the restarted workloads
+func RotateDatabaseCredentials(ctx context.Context, client k8s.Interface, namespace string, db *DatabaseStorage, timeout time.Duration, onLog func(line string)) ([]kubernetes.Workload, error) {
+	connection, ok := databaseConnections[db.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type: %s", db.Type)
+	}
+
+	secrets := client.CoreV1().Secrets(namespace)
+	name := databaseSecretName(db.Name)
+	secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("the credentials of database %s were not found, deploy the database first", db.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// stage the new password
+	password, err := generatePassword()
+	if err != nil {
+		return nil, err
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[databaseNextPasswordKey] = []byte(password)
+	if secret, err = secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+
+	job := kubernetes.NewJob(&kubernetes.JobSpec{
+		Name:      db.Name + "-rotate-credentials",
+		Namespace: namespace,
+		Image:     databaseRotationImage,
+		Command:   []string{"sh", "-c", databaseRotationScript},
+		Env: map[string]string{
+			"PGHOST":     databaseHost(db.Name, namespace),
+			"PGPORT":     fmt.Sprint(connection.port),
+			"PGUSER":     databaseUser,
+			"PGDATABASE": connection.database,
+		},
+		SecretEnv: map[string]*kubernetes.SecretKeyRef{
+			"PGPASSWORD":   {Name: name, Key: databasePasswordKey},
+			"NEW_PASSWORD": {Name: name, Key: databaseNextPasswordKey},
+		},
+		Labels: databaseSecretLabels(db.Name),
+	})
+	watcher := kubernetes.NewRolloutWatcher(client)
+	jobErr := watcher.RunJob(ctx, job, timeout, onLog)
+
+	// apply the new password only if the database accepted it
+	if jobErr == nil {
+		secret.Data[databasePasswordKey] = secret.Data[databaseNextPasswordKey]
+	}
+	delete(secret.Data, databaseNextPasswordKey)
+	if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		if jobErr == nil {
+			return nil, fmt.Errorf("the password of database %s was changed but not stored in secret %s: %w", db.Name, name, err)
+		}
+		return nil, err
+	}
+	if jobErr != nil {
+		return nil, fmt.Errorf("failed to change the password of database %s: %w", db.Name, jobErr)
+	}
+
+	// restart the services and the database pooler with the new password
+	workloads, err := kubernetes.RestartWorkloadsUsingSecret(ctx, client, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return workloads, watcher.Wait(ctx, workloads, timeout)
+}
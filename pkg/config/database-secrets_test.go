@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestGeneratePassword returns random passwords safe in connection strings
+func TestGeneratePassword(t *testing.T) {
+	first, err := generatePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := generatePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != databasePasswordLength {
+		t.Errorf("expected a %d characters password, got %d", databasePasswordLength, len(first))
+	}
+	if strings.Trim(first, databasePasswordAlphabet) != "" {
+		t.Errorf("expected only alphanumeric characters, got %s", first)
+	}
+	if first == second {
+		t.Errorf("expected random passwords, got %s twice", first)
+	}
+}
+
+// TestEnsureDatabaseCredentials generates the credentials once
+func TestEnsureDatabaseCredentials(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	created, err := EnsureDatabaseCredentials(ctx, client, "nopeus-app", "main")
+	if err != nil || !created {
+		t.Fatalf("expected the credentials to be generated, got %v, %v", created, err)
+	}
+	if _, err := client.CoreV1().Namespaces().Get(ctx, "nopeus-app", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the namespace to be created: %v", err)
+	}
+
+	secret, err := client.CoreV1().Secrets("nopeus-app").Get(ctx, "database-secrets-main", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{databasePasswordKey, databasePostgresPasswordKey, databaseRepmgrPasswordKey, databaseAdminPasswordKey} {
+		if secret.StringData[key] == "" {
+			t.Errorf("expected the %s key to be generated", key)
+		}
+	}
+
+	created, err = EnsureDatabaseCredentials(ctx, client, "nopeus-app", "main")
+	if err != nil || created {
+		t.Fatalf("expected the existing credentials to be kept, got %v, %v", created, err)
+	}
+	kept, err := client.CoreV1().Secrets("nopeus-app").Get(ctx, "database-secrets-main", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept.StringData[databasePasswordKey] != secret.StringData[databasePasswordKey] {
+		t.Errorf("expected the password to be kept across deployments")
+	}
+}
+
+// TestRotateDatabaseCredentialsMissingSecret requires the database to be deployed
+func TestRotateDatabaseCredentialsMissingSecret(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	db := &DatabaseStorage{Name: "main", Type: "postgres"}
+
+	if _, err := RotateDatabaseCredentials(context.Background(), client, "nopeus-app", db, 0, nil); err == nil {
+		t.Errorf("expected an error for a database without credentials")
+	}
+}
+
+// TestRotateDatabaseCredentialsHost connects the rotation job to the pgpool service
+func TestRotateDatabaseCredentialsHost(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	if _, err := EnsureDatabaseCredentials(ctx, client, "nopeus-app", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	var env []corev1.EnvVar
+	client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		env = job.Spec.Template.Spec.Containers[0].Env
+		return true, nil, fmt.Errorf("job rejected")
+	})
+
+	db := &DatabaseStorage{Name: "main", Type: "postgres"}
+	if _, err := RotateDatabaseCredentials(ctx, client, "nopeus-app", db, 0, nil); err == nil {
+		t.Fatal("expected the rejected job to fail the rotation")
+	}
+
+	values := map[string]string{}
+	for _, envVar := range env {
+		values[envVar.Name] = envVar.Value
+	}
+	if values["PGHOST"] != "main-postgresql-ha-pgpool.nopeus-app.svc.cluster.local" || values["PGDATABASE"] != "postgres" {
+		t.Errorf("expected the pgpool host and the postgres database, got %v", values)
+	}
+}
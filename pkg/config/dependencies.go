@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the amount of releases applied in parallel by default
+const defaultConcurrency = 4
+
+// define the releases the services rely on, e.g., the api gateway
+type infrastructureRelease interface {
+	IsInfrastructure() bool
+}
+
+// return true if the release is part of the infrastructure
+func isInfrastructure(service ServiceTemplateData) bool {
+	release, ok := service.(infrastructureRelease)
+	return ok && release.IsInfrastructure()
+}
+
+// sort the services into rollout batches based on their dependencies.
+// every service is placed after the services it depends on, the services
+// of a single batch are independent and can be applied in parallel.
+// the infrastructure releases are placed in their own batches first
+func SortByDependencies(services []ServiceTemplateData) ([][]ServiceTemplateData, error) {
+	byName := make(map[string]ServiceTemplateData)
+	for _, service := range services {
+		if _, ok := byName[service.GetName()]; ok {
+			return nil, fmt.Errorf("service %s is defined more than once", service.GetName())
+		}
+		byName[service.GetName()] = service
+	}
+
+	// validate the dependencies exist
+	for _, service := range services {
+		for _, dependency := range service.GetDependencies() {
+			if _, ok := byName[dependency]; !ok {
+				return nil, fmt.Errorf("service %s depends on unknown service %s", service.GetName(), dependency)
+			}
+			if isInfrastructure(service) && !isInfrastructure(byName[dependency]) {
+				return nil, fmt.Errorf("infrastructure release %s can't depend on service %s", service.GetName(), dependency)
+			}
+		}
+	}
+
+	if cycle := findDependencyCycle(services, byName); cycle != nil {
+		return nil, fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+
+	// assign each service the batch after its latest dependency
+	// and after the batches of the infrastructure releases
+	levels := make(map[string]int)
+	infrastructureBatches := 0
+	var level func(service ServiceTemplateData) int
+	level = func(service ServiceTemplateData) int {
+		if l, ok := levels[service.GetName()]; ok {
+			return l
+		}
+
+		l := 0
+		if !isInfrastructure(service) {
+			l = infrastructureBatches
+		}
+		for _, dependency := range service.GetDependencies() {
+			if dl := level(byName[dependency]) + 1; dl > l {
+				l = dl
+			}
+		}
+		levels[service.GetName()] = l
+		return l
+	}
+
+	for _, service := range services {
+		if isInfrastructure(service) && level(service)+1 > infrastructureBatches {
+			infrastructureBatches = level(service) + 1
+		}
+	}
+
+	batches := [][]ServiceTemplateData{}
+	for _, service := range services {
+		l := level(service)
+		for len(batches) <= l {
+			batches = append(batches, []ServiceTemplateData{})
+		}
+		batches[l] = append(batches[l], service)
+	}
+
+	// keep the order of each batch stable between runs
+	for _, batch := range batches {
+		sort.Slice(batch, func(i, j int) bool { return batch[i].GetName() < batch[j].GetName() })
+	}
+
+	return batches, nil
+}
+
+// return the names of the services in each rollout batch
+func GetBatchNames(batches [][]ServiceTemplateData) [][]string {
+	names := [][]string{}
+	for _, batch := range batches {
+		batchNames := []string{}
+		for _, service := range batch {
+			batchNames = append(batchNames, service.GetName())
+		}
+		names = append(names, batchNames)
+	}
+
+	return names
+}
+
+// return the first dependency cycle found as a path, e.g., [a b a]
+// or nil if the dependencies form a valid graph
+func findDependencyCycle(services []ServiceTemplateData, byName map[string]ServiceTemplateData) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	path := []string{}
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			// the cycle starts where the service first appears in the path
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range byName[name].GetDependencies() {
+			if cycle := visit(dependency); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, service := range services {
+		if state[service.GetName()] == unvisited {
+			if cycle := visit(service.GetName()); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
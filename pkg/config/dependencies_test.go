@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// create a service with the given dependencies
+func newDependentService(name string, dependsOn ...string) ServiceTemplateData {
+	return &NopeusDefaultMicroservice{Name: name, dependsOn: dependsOn}
+}
+
+// TestSortByDependencies places each service after its dependencies
+func TestSortByDependencies(t *testing.T) {
+	services := []ServiceTemplateData{
+		newDependentService("web", "api"),
+		newDependentService("api", "postgres", "redis"),
+		newDependentService("worker", "postgres"),
+		&NopeusStorageMicroservice{Name: "postgres"},
+		newDependentService("redis"),
+	}
+
+	batches, err := SortByDependencies(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{{"postgres", "redis"}, {"api", "worker"}, {"web"}}
+	if names := GetBatchNames(batches); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+// TestSortByDependenciesCycle reports the dependency cycle
+func TestSortByDependenciesCycle(t *testing.T) {
+	services := []ServiceTemplateData{
+		newDependentService("api", "worker"),
+		newDependentService("worker", "queue"),
+		newDependentService("queue", "api"),
+	}
+
+	_, err := SortByDependencies(services)
+	if err == nil || !strings.Contains(err.Error(), "api -> worker -> queue -> api") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+}
+
+// TestSortByDependenciesUnknown rejects unknown dependencies
+func TestSortByDependenciesUnknown(t *testing.T) {
+	_, err := SortByDependencies([]ServiceTemplateData{newDependentService("api", "postgres")})
+	if err == nil || !strings.Contains(err.Error(), "unknown service postgres") {
+		t.Errorf("expected an unknown dependency error, got %v", err)
+	}
+}
+
+// create an infrastructure release with the given dependencies
+func newInfrastructureRelease(name string, dependsOn ...string) ServiceTemplateData {
+	return &NopeusDefaultMicroservice{Name: name, dependsOn: dependsOn, infrastructure: true}
+}
+
+// TestSortByDependenciesInfrastructure applies the infrastructure releases first
+func TestSortByDependenciesInfrastructure(t *testing.T) {
+	services := []ServiceTemplateData{
+		newDependentService("web", "api"),
+		newDependentService("api"),
+		&NopeusStorageMicroservice{Name: "postgres"},
+		newInfrastructureRelease("prometheus-adapter", "prometheus"),
+		newInfrastructureRelease("prometheus"),
+		newInfrastructureRelease("api-gateway"),
+		newInfrastructureRelease("cert-manager-nopeus"),
+	}
+
+	batches, err := SortByDependencies(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"api-gateway", "cert-manager-nopeus", "prometheus"},
+		{"prometheus-adapter"},
+		{"api", "postgres"},
+		{"web"},
+	}
+	if names := GetBatchNames(batches); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	// an infrastructure release can't wait for the services it is applied before
+	services = append(services, newInfrastructureRelease("ingress-controller", "api"))
+	if _, err := SortByDependencies(services); err == nil || !strings.Contains(err.Error(), "can't depend on service api") {
+		t.Errorf("expected an infrastructure dependency error, got %v", err)
+	}
+}
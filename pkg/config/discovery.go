@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the user the databases are created with, see storage.values.yaml
+const databaseUser = "nopeus"
+
+// define the connection defaults of each supported database type
+var databaseConnections = map[string]struct {
+	scheme   string
+	port     int
+	database string
+}{
+	"postgres": {scheme: "postgresql", port: 5432, database: "postgres"},
+}
+
+// the dependency of the database chart deploying postgres
+const databaseSubchart = "postgresql-ha"
+
+// the maximum length of the kubernetes service names
+const maxServiceNameLength = 63
+
+// define the variables a service is injected with to reach
+// the services and databases exposed to it
+type DiscoveryVariables struct {
+	// the plain variables, e.g., API_URL
+	Environment map[string]string
+
+	// the variables read from the database secrets
+	Secrets []*SecretEnvVar
+}
+
+// return the prefix of the discovery variables of a service, e.g., API_GATEWAY
+func discoveryPrefix(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// return the in-cluster dns name of a service
+func serviceHost(name string, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", name, namespace)
+}
+
+// return the in-cluster dns name of the pgpool service in front of a database.
+// the postgresql-ha dependency names its services after the release like the
+// fullname helper of the chart - prefixed by the release name unless it
+// contains the chart name, truncated to a valid service name
+func databaseHost(name string, namespace string) string {
+	fullname := name
+	if !strings.Contains(name, databaseSubchart) {
+		fullname = name + "-" + databaseSubchart
+	}
+
+	return serviceHost(truncateServiceName(truncateServiceName(fullname)+"-pgpool"), namespace)
+}
+
+// truncate the name to a valid service name like the helm charts do
+func truncateServiceName(name string) string {
+	if len(name) > maxServiceNameLength {
+		name = name[:maxServiceNameLength]
+	}
+
+	return strings.TrimSuffix(name, "-")
+}
+
+// return true if the consumer can see the variables of the service
+// a service without expose_to is exposed to every service
+func isExposedTo(exposeTo []string, consumer string) bool {
+	if len(exposeTo) == 0 {
+		return true
+	}
+
+	for _, name := range exposeTo {
+		if name == consumer {
+			return true
+		}
+	}
+	return false
+}
+
+// validate the expose_to list only references known services
+func validateExposeTo(owner string, exposeTo []string, services map[string]*Service) error {
+	for _, name := range exposeTo {
+		if _, ok := services[name]; !ok {
+			return fmt.Errorf("%s is exposed to the unknown service %s", owner, name)
+		}
+	}
+
+	return nil
+}
+
+// return the discovery variables of the services and databases exposed to the
+// consumer. e.g., for the api service and the main database:
+//
+//	API_HOST, API_PORT, API_URL
+//	MAIN_HOST, MAIN_PORT, MAIN_DATABASE_PASSWORD, MAIN_DATABASE_URL
+func (c *CloudApplicationLayerConfig) GetDiscoveryVariables(consumer string, envName string, namespace string) (*DiscoveryVariables, error) {
+	services, err := c.GetServices()
+	if err != nil {
+		return nil, err
+	}
+
+	discovery := &DiscoveryVariables{Environment: make(map[string]string), Secrets: []*SecretEnvVar{}}
+
+	names := []string{}
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		service := services[name]
+		if err := validateExposeTo("service "+name, service.ExposeTo, services); err != nil {
+			return nil, err
+		}
+
+		// only long running services are reachable through a kubernetes service
+		if name == consumer || service.GetKind() != ServiceKindService || !isExposedTo(service.ExposeTo, consumer) {
+			continue
+		}
+
+		prefix := discoveryPrefix(name)
+		host := serviceHost(name, namespace)
+		discovery.Environment[prefix+"_HOST"] = host
+
+		ports, err := service.GetPorts(envName)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		if len(ports) > 0 {
+			discovery.Environment[prefix+"_PORT"] = strconv.Itoa(ports[0].Port)
+			discovery.Environment[prefix+"_URL"] = fmt.Sprintf("%s://%s:%d", ports[0].GetProtocol(), host, ports[0].Port)
+		}
+	}
+
+	if c.GetStorage() == nil {
+		return discovery, nil
+	}
+
+	for _, db := range c.GetStorage().Database {
+		if err := validateExposeTo("database "+db.Name, db.ExposeTo, services); err != nil {
+			return nil, err
+		}
+		if !isExposedTo(db.ExposeTo, consumer) {
+			continue
+		}
+
+		connection, ok := databaseConnections[db.Type]
+		if !ok {
+			return nil, fmt.Errorf("unsupported database type: %s", db.Type)
+		}
+
+		prefix := discoveryPrefix(db.Name)
+		host := databaseHost(db.Name, namespace)
+		discovery.Environment[prefix+"_HOST"] = host
+		discovery.Environment[prefix+"_PORT"] = strconv.Itoa(connection.port)
+
+		// the password is only read from the database secret in the cluster and
+		// expanded by kubernetes into the url, which requires the password
+		// variable to be listed first - the secret variables and the variables
+		// expanding them are appended to the environment in this order
+		password := prefix + "_DATABASE_PASSWORD"
+		discovery.Secrets = append(discovery.Secrets, &SecretEnvVar{Name: password, Secret: databaseSecretName(db.Name), Key: databasePasswordKey})
+		discovery.Environment[prefix+"_DATABASE_URL"] = fmt.Sprintf("%s://%s:$(%s)@%s:%d/%s", connection.scheme, databaseUser, password, host, connection.port, connection.database)
+	}
+
+	return discovery, nil
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// define the supported env file encryptions
+const (
+	// a plaintext dotenv file
+	EnvFileEncryptionNone = ""
+
+	// a dotenv file with values encrypted by sops
+	EnvFileEncryptionSOPS = "sops"
+
+	// a dotenv file encrypted as a whole by age
+	EnvFileEncryptionAge = "age"
+)
+
+// the environment variables holding the age identity, shared with sops
+const (
+	// the path of the age identity file
+	ageKeyFileEnv = "SOPS_AGE_KEY_FILE"
+
+	// the age identity itself
+	ageKeyEnv = "SOPS_AGE_KEY"
+)
+
+// the environment variable holding the path of the age recipients
+// file an edited env file is encrypted to
+const ageRecipientsFileEnv = "NOPEUS_AGE_RECIPIENTS_FILE"
+
+// the exit code of sops when the edited file was left as is
+const sopsFileNotModifiedCode = 200
+
+// the headers of the binary and the armored age formats
+var ageHeaders = [][]byte{
+	[]byte("age-encryption.org/v1\n"),
+	[]byte("-----BEGIN AGE ENCRYPTED FILE-----"),
+}
+
+// run an external command with the given input and return its output
+// replaced in the tests to avoid depending on the sops and age binaries
+var runCommand = func(input []byte, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s not found in PATH - install %s to use encrypted env files", name, name)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return output, nil
+}
+
+// run an external command attached to the terminal, e.g., an editor, with
+// the given variables added to the environment. replaced in the tests
+var runInteractiveCommand = func(env []string, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not found in PATH - install %s to edit encrypted env files", name, name)
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// return the encryption of the env file content
+func DetectEnvFileEncryption(content []byte) string {
+	for _, header := range ageHeaders {
+		if bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), header) {
+			return EnvFileEncryptionAge
+		}
+	}
+
+	// sops stores its metadata next to the values, e.g., sops_version=3.7.3
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if strings.HasPrefix(strings.TrimSpace(scanner.Text()), "sops_version=") {
+			return EnvFileEncryptionSOPS
+		}
+	}
+
+	return EnvFileEncryptionNone
+}
+
+// read the env file and decrypt it if required
+func ReadEnvironmentFile(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := DecryptEnvironmentFile(file, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the env file %s: %w", file, err)
+	}
+
+	return godotenv.Unmarshal(string(plaintext))
+}
+
+// decrypt the content of the env file based on its encryption
+func DecryptEnvironmentFile(file string, content []byte) ([]byte, error) {
+	switch DetectEnvFileEncryption(content) {
+	case EnvFileEncryptionSOPS:
+		// sops finds the age identity in the same environment variables
+		return runCommand(nil, "sops", "--decrypt", "--input-type", "dotenv", "--output-type", "dotenv", file)
+
+	case EnvFileEncryptionAge:
+		identity, cleanup, err := ageIdentityFile()
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		return runCommand(content, "age", "--decrypt", "--identity", identity)
+
+	default:
+		return content, nil
+	}
+}
+
+// encrypt the plaintext env file with age to the recipients of the given
+// recipients file, armored like the given encrypted content
+func EncryptEnvironmentFile(file string, encrypted []byte, recipientsFile string) ([]byte, error) {
+	if DetectEnvFileEncryption(encrypted) != EnvFileEncryptionAge {
+		return nil, fmt.Errorf("only age encrypted env files are encrypted by nopeus - sops files are edited by sops")
+	}
+	if recipientsFile == "" {
+		return nil, fmt.Errorf("no age recipients - set env_file_recipients or %s", ageRecipientsFileEnv)
+	}
+
+	args := []string{"--encrypt", "--recipients-file", recipientsFile}
+	if bytes.HasPrefix(bytes.TrimLeft(encrypted, " \t\r\n"), ageHeaders[1]) {
+		args = append(args, "--armor")
+	}
+	return runCommand(nil, "age", append(args, file)...)
+}
+
+// edit the encrypted env file with the given editor command, e.g., code --wait.
+// sops files are edited by sops which keeps their keys and metadata. age files
+// are encrypted back to the given recipients file, or the one set by the
+// environment, since the recipients can't be read from the encrypted file.
+// returns false if nothing was changed
+func EditEnvironmentFile(file string, editor string, recipientsFile string) (bool, error) {
+	encrypted, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+
+	switch DetectEnvFileEncryption(encrypted) {
+	case EnvFileEncryptionSOPS:
+		return editSOPSFile(file, encrypted, editor)
+
+	case EnvFileEncryptionAge:
+		if recipientsFile == "" {
+			recipientsFile = os.Getenv(ageRecipientsFileEnv)
+		}
+		if recipientsFile == "" {
+			return false, fmt.Errorf("the recipients of the age encrypted env file %s are unknown - set env_file_recipients or %s to re-encrypt it", file, ageRecipientsFileEnv)
+		}
+		return editAgeFile(file, encrypted, editor, recipientsFile)
+
+	default:
+		return false, fmt.Errorf("the env file %s is not encrypted", file)
+	}
+}
+
+// let sops decrypt the file, open the editor and encrypt it back
+func editSOPSFile(file string, encrypted []byte, editor string) (bool, error) {
+	// sops exits with a dedicated code when the file wasn't modified
+	err := runInteractiveCommand([]string{"EDITOR=" + editor}, "sops", "--input-type", "dotenv", "--output-type", "dotenv", file)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == sopsFileNotModifiedCode {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to edit the env file %s: %w", file, err)
+	}
+
+	edited, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(edited, encrypted), nil
+}
+
+// decrypt the age file into a private temporary file, open the
+// editor and encrypt it back to the recipients of the recipients file
+func editAgeFile(file string, encrypted []byte, editor string, recipientsFile string) (bool, error) {
+	plaintext, err := DecryptEnvironmentFile(file, encrypted)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt the env file %s: %w", file, err)
+	}
+
+	// the plaintext never leaves the private temporary directory
+	dir, err := os.MkdirTemp("", "nopeus-secrets-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmpfile := filepath.Join(dir, filepath.Base(file))
+	if err := os.WriteFile(tmpfile, plaintext, 0o600); err != nil {
+		return false, err
+	}
+	command := strings.Fields(editor)
+	if len(command) == 0 {
+		return false, fmt.Errorf("no editor to open the env file with")
+	}
+	if err := runInteractiveCommand(nil, command[0], append(command[1:], tmpfile)...); err != nil {
+		return false, err
+	}
+
+	edited, err := os.ReadFile(tmpfile)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(edited, plaintext) {
+		return false, nil
+	}
+	if _, err := godotenv.Unmarshal(string(edited)); err != nil {
+		return false, fmt.Errorf("invalid env file: %w", err)
+	}
+
+	reencrypted, err := EncryptEnvironmentFile(tmpfile, encrypted, recipientsFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt the env file %s: %w", file, err)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(file, reencrypted, info.Mode().Perm())
+}
+
+// return the path of the age identity file. an identity set by the environment
+// variable is written to a private temporary file, removed by the cleanup func
+func ageIdentityFile() (string, func(), error) {
+	if key := os.Getenv(ageKeyEnv); key != "" {
+		file, err := os.CreateTemp("", "nopeus-age-*")
+		if err != nil {
+			return "", nil, err
+		}
+		cleanup := func() { os.Remove(file.Name()) }
+
+		_, err = file.WriteString(key + "\n")
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			cleanup()
+			return "", nil, err
+		}
+
+		return file.Name(), cleanup, nil
+	}
+
+	if file := os.Getenv(ageKeyFileEnv); file != "" {
+		return file, func() {}, nil
+	}
+
+	// the default location of the sops age keys
+	configDir, err := os.UserConfigDir()
+	if err == nil {
+		file := filepath.Join(configDir, "sops", "age", "keys.txt")
+		if _, err := os.Stat(file); err == nil {
+			return file, func() {}, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("no age identity found - set %s or %s", ageKeyFileEnv, ageKeyEnv)
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the sops metadata of an age encrypted dotenv file
+const sopsEnvFile = `API_KEY=ENC[AES256_GCM,data:abc,iv:def,tag:ghi,type:str]
+sops_age__list_0__map_recipient=age1second
+sops_age__list_1__map_recipient=age1first
+sops_lastmodified=2022-08-01T00:00:00Z
+sops_mac=ENC[AES256_GCM,data:jkl,iv:mno,tag:pqr,type:str]
+sops_version=3.7.3
+`
+
+// stub the sops and age commands for the test
+func stubRunCommand(t *testing.T, run func(input []byte, name string, args ...string) ([]byte, error)) {
+	original := runCommand
+	runCommand = run
+	t.Cleanup(func() { runCommand = original })
+}
+
+// TestDetectEnvFileEncryption detects sops, age and plaintext env files
+func TestDetectEnvFileEncryption(t *testing.T) {
+	tests := map[string]string{
+		"API_KEY=plain\n":                        EnvFileEncryptionNone,
+		sopsEnvFile:                              EnvFileEncryptionSOPS,
+		"age-encryption.org/v1\n-> X25519 abc\n": EnvFileEncryptionAge,
+		"-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n-----END AGE": EnvFileEncryptionAge,
+	}
+	for content, expected := range tests {
+		if encryption := DetectEnvFileEncryption([]byte(content)); encryption != expected {
+			t.Errorf("expected %q encryption for %q, got %q", expected, content, encryption)
+		}
+	}
+}
+
+// TestReadEnvironmentFileAge decrypts an age file with the identity of the env var
+func TestReadEnvironmentFileAge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(file, []byte("age-encryption.org/v1\nciphertext"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ageKeyEnv, "AGE-SECRET-KEY-TEST")
+
+	stubRunCommand(t, func(input []byte, name string, args ...string) ([]byte, error) {
+		if name != "age" || args[0] != "--decrypt" || args[1] != "--identity" {
+			t.Fatalf("unexpected command %s %v", name, args)
+		}
+		identity, err := os.ReadFile(args[2])
+		if err != nil || strings.TrimSpace(string(identity)) != "AGE-SECRET-KEY-TEST" {
+			t.Errorf("expected the identity of the env var, got %q, %v", identity, err)
+		}
+		if string(input) != "age-encryption.org/v1\nciphertext" {
+			t.Errorf("expected the encrypted content as input, got %q", input)
+		}
+		return []byte("API_KEY=s3cr3t\n"), nil
+	})
+
+	values, err := ReadEnvironmentFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["API_KEY"] != "s3cr3t" {
+		t.Errorf("expected the decrypted values, got %v", values)
+	}
+}
+
+// TestReadEnvironmentFileMissingIdentity fails without an age identity
+func TestReadEnvironmentFileMissingIdentity(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(file, []byte("age-encryption.org/v1\nciphertext"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ageKeyEnv, "")
+	t.Setenv(ageKeyFileEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := ReadEnvironmentFile(file); err == nil || !strings.Contains(err.Error(), "no age identity") {
+		t.Errorf("expected a missing identity error, got %v", err)
+	}
+}
+
+// TestLoadEnvironmentFileSOPS loads the sops decrypted values without overriding the environment
+func TestLoadEnvironmentFileSOPS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "prod.env"), []byte(sopsEnvFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_ENVFILE_REGION", "eu-west-1")
+	t.Setenv("TEST_ENVFILE_API_KEY", "")
+	os.Unsetenv("TEST_ENVFILE_API_KEY")
+
+	stubRunCommand(t, func(input []byte, name string, args ...string) ([]byte, error) {
+		if name != "sops" || args[0] != "--decrypt" || args[len(args)-1] != filepath.Join(dir, "prod.env") {
+			t.Fatalf("unexpected command %s %v", name, args)
+		}
+		return []byte("TEST_ENVFILE_API_KEY=s3cr3t\nTEST_ENVFILE_REGION=us-east-1\n"), nil
+	})
+
+	env := &EnvironmentConfig{EnvFileLocation: "prod.env"}
+	if err := env.LoadEnvironmentFile(dir); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("TEST_ENVFILE_API_KEY") != "s3cr3t" {
+		t.Errorf("expected the decrypted value in the environment, got %q", os.Getenv("TEST_ENVFILE_API_KEY"))
+	}
+	if os.Getenv("TEST_ENVFILE_REGION") != "eu-west-1" {
+		t.Errorf("expected the environment to take precedence, got %q", os.Getenv("TEST_ENVFILE_REGION"))
+	}
+}
+
+// stub the editor and sops commands attached to the terminal for the test
+func stubRunInteractiveCommand(t *testing.T, run func(env []string, name string, args ...string) error) {
+	original := runInteractiveCommand
+	runInteractiveCommand = run
+	t.Cleanup(func() { runInteractiveCommand = original })
+}
+
+// TestEditEnvironmentFileSOPS lets sops edit the file to keep its keys and metadata
+func TestEditEnvironmentFileSOPS(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "prod.env")
+	if err := os.WriteFile(file, []byte(sopsEnvFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stubRunCommand(t, func(input []byte, name string, args ...string) ([]byte, error) {
+		t.Fatalf("unexpected command %s %v", name, args)
+		return nil, nil
+	})
+	stubRunInteractiveCommand(t, func(env []string, name string, args ...string) error {
+		if name != "sops" || strings.Join(args, " ") != "--input-type dotenv --output-type dotenv "+file {
+			t.Fatalf("unexpected command %s %v", name, args)
+		}
+		if strings.Join(env, " ") != "EDITOR=code --wait" {
+			t.Errorf("expected the editor of the user, got %v", env)
+		}
+		return os.WriteFile(file, []byte("API_KEY=ENC[new]\nsops_version=3.7.3\n"), 0o600)
+	})
+
+	changed, err := EditEnvironmentFile(file, "code --wait", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Errorf("expected the file to change")
+	}
+}
+
+// TestEditEnvironmentFileAge encrypts the edited file to the configured recipients
+func TestEditEnvironmentFileAge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "prod.env")
+	encrypted := "-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n-----END AGE ENCRYPTED FILE-----\n"
+	if err := os.WriteFile(file, []byte(encrypted), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ageKeyEnv, "AGE-SECRET-KEY-TEST")
+
+	stubRunCommand(t, func(input []byte, name string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "--decrypt":
+			return []byte("API_KEY=old\n"), nil
+		case "--encrypt":
+			if strings.Join(args[:len(args)-1], " ") != "--encrypt --recipients-file recipients.txt --armor" {
+				t.Errorf("expected the encryption to the recipients file, got %v", args)
+			}
+			content, err := os.ReadFile(args[len(args)-1])
+			if err != nil || string(content) != "API_KEY=new\n" {
+				t.Errorf("expected the edited plaintext, got %q, %v", content, err)
+			}
+			return []byte("-----BEGIN AGE ENCRYPTED FILE-----\nbmV3\n"), nil
+		}
+		t.Fatalf("unexpected command %s %v", name, args)
+		return nil, nil
+	})
+
+	var tmpfile string
+	stubRunInteractiveCommand(t, func(env []string, name string, args ...string) error {
+		if name != "code" || args[0] != "--wait" {
+			t.Fatalf("unexpected editor %s %v", name, args)
+		}
+		tmpfile = args[1]
+		return os.WriteFile(tmpfile, []byte("API_KEY=new\n"), 0o600)
+	})
+
+	changed, err := EditEnvironmentFile(file, "code --wait", "recipients.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Errorf("expected the file to change")
+	}
+	if content, _ := os.ReadFile(file); string(content) != "-----BEGIN AGE ENCRYPTED FILE-----\nbmV3\n" {
+		t.Errorf("expected the encrypted content in the env file, got %q", content)
+	}
+	if _, err := os.Stat(tmpfile); !os.IsNotExist(err) {
+		t.Errorf("expected the plaintext file to be removed, got %v", err)
+	}
+}
+
+// TestEditEnvironmentFileAgeWithoutRecipients refuses to re-encrypt to unknown recipients
+func TestEditEnvironmentFileAgeWithoutRecipients(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "prod.env")
+	if err := os.WriteFile(file, []byte("age-encryption.org/v1\nciphertext"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ageRecipientsFileEnv, "")
+
+	stubRunInteractiveCommand(t, func(env []string, name string, args ...string) error {
+		t.Fatalf("unexpected command %s %v", name, args)
+		return nil
+	})
+
+	if _, err := EditEnvironmentFile(file, "vi", ""); err == nil || !strings.Contains(err.Error(), ageRecipientsFileEnv) {
+		t.Errorf("expected a missing recipients error, got %v", err)
+	}
+}
+
+// TestEditEnvironmentFilePlaintext rejects plaintext env files
+func TestEditEnvironmentFilePlaintext(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(file, []byte("API_KEY=plain\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := EditEnvironmentFile(file, "vi", ""); err == nil {
+		t.Errorf("expected an error for a plaintext env file")
+	}
+}
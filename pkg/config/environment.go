@@ -1,11 +1,13 @@
 package config
 
 import (
+	"encoding/json"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/terraform-exec/tfexec"
-	"github.com/joho/godotenv"
 	"github.com/salfatigroup/nopeus/helm"
 	"github.com/salfatigroup/nopeus/logger"
 )
@@ -16,6 +18,15 @@ type EnvironmentConfig struct {
 	kubeContext     string
 	checksumMap     map[string]string
 	outputs         map[string]tfexec.OutputMeta
+	extraOutputs    []string
+
+	// guards the checksum map while services are applied in parallel
+	checksumMutex sync.Mutex
+
+	// the age recipients file, one recipient per line, an edited age
+	// encrypted env file is encrypted to. relative to the nopeus.yaml
+	// directory, defaults to $NOPEUS_AGE_RECIPIENTS_FILE
+	EnvFileRecipients string `yaml:"env_file_recipients"`
 }
 
 func NewEnvironmentConfig() *EnvironmentConfig {
@@ -32,12 +43,47 @@ func (i *EnvironmentConfig) GetOutputs() map[string]tfexec.OutputMeta {
 	return i.outputs
 }
 
+// returns the terraform outputs as strings, nil until terraform has run.
+// string outputs use their raw value and the other outputs their json value
+func (i *EnvironmentConfig) GetOutputValues() map[string]string {
+	if i.outputs == nil {
+		return nil
+	}
+
+	values := make(map[string]string)
+	for name, output := range i.outputs {
+		var value string
+		if err := json.Unmarshal(output.Value, &value); err != nil {
+			value = string(output.Value)
+		}
+		values[name] = value
+	}
+
+	return values
+}
+
+// set the names of the terraform outputs defined in the user supplied terraform dirs
+func (i *EnvironmentConfig) SetExtraOutputs(names []string) {
+	i.extraOutputs = names
+}
+
+// returns the names of the terraform outputs that should be exposed to the services
+func (i *EnvironmentConfig) GetExtraOutputs() []string {
+	return i.extraOutputs
+}
+
 // returns the env file to load for the environment
 func (i *EnvironmentConfig) GetEnvFileLocation() string {
 	return i.EnvFileLocation
 }
 
+// returns the age recipients file of the env file
+func (i *EnvironmentConfig) GetEnvFileRecipients() string {
+	return i.EnvFileRecipients
+}
+
 // load the environment file only if env_file location was provided
+// sops and age encrypted files are decrypted with the local age identity
 func (i *EnvironmentConfig) LoadEnvironmentFile(basepath string) error {
 	if i.EnvFileLocation == "" {
 		return nil
@@ -47,8 +93,26 @@ func (i *EnvironmentConfig) LoadEnvironmentFile(basepath string) error {
 	file := filepath.Join(basepath, i.EnvFileLocation)
 	logger.Debugf("Loading environment file %s", file)
 
-	// load the dotenv files if they exists
-	return godotenv.Load(file)
+	// every value in the env file is considered sensitive
+	values, err := ReadEnvironmentFile(file)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		logger.RegisterSensitive(value)
+	}
+
+	// like dotenv, the variables already set in the environment take precedence
+	for key, value := range values {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Set the KubeContext to the envData
@@ -92,7 +156,27 @@ func (i *EnvironmentConfig) LoadChecksumMap() error {
 
 // get service checksum by name
 func (i *EnvironmentConfig) GetChecksum(name string) string {
+	i.checksumMutex.Lock()
+	defer i.checksumMutex.Unlock()
+
 	hash := i.checksumMap[name]
 	logger.Debugf("Service %s checksum is %s", name, hash)
 	return hash
 }
+
+// set the checksum of a service that was deployed successfully
+func (i *EnvironmentConfig) SetChecksum(name string, checksum string) {
+	i.checksumMutex.Lock()
+	defer i.checksumMutex.Unlock()
+
+	if i.checksumMap == nil {
+		i.checksumMap = make(map[string]string)
+	}
+
+	i.checksumMap[name] = checksum
+}
+
+// return the checksums of the services that are deployed successfully
+func (i *EnvironmentConfig) GetChecksumMap() map[string]string {
+	return i.checksumMap
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/salfatigroup/nopeus/kubernetes"
+	"github.com/salfatigroup/nopeus/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the maximum size of the files of a service, the size limit of a configmap
+const maxFilesSize = 1 << 20
+
+// the characters that are not allowed in a configmap key
+var configMapKeyRegexp = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// define the configmap holding the files of a service
+type ServiceFiles struct {
+	// the service the files belong to
+	Service string
+
+	// the configmap name, changes with the content of the files
+	Name string
+
+	// the content of the files by their configmap key
+	Data map[string]string
+}
+
+// load the files of the service, relative to the given base path, into
+// a configmap and return the volumes mounting each file to its mount path
+func LoadServiceFiles(basepath string, service string, files map[string]string) (*ServiceFiles, []*Volume, error) {
+	if len(files) == 0 {
+		return nil, nil, nil
+	}
+
+	// the keys are sorted for a stable configmap name
+	mountPaths := make(map[string]string)
+	for file, mountPath := range files {
+		if !path.IsAbs(mountPath) {
+			return nil, nil, fmt.Errorf("the file %s requires an absolute mount path", file)
+		}
+		key := configMapKey(mountPath)
+		if _, ok := mountPaths[key]; ok {
+			return nil, nil, fmt.Errorf("more than one file is mounted to %s", mountPath)
+		}
+		mountPaths[key] = file
+	}
+	keys := []string{}
+	for key := range mountPaths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	serviceFiles := &ServiceFiles{Service: service, Data: make(map[string]string)}
+	volumes := []*Volume{}
+	hash := sha256.New()
+	size := 0
+	for _, key := range keys {
+		file := mountPaths[key]
+		content, err := os.ReadFile(filepath.Join(basepath, file))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the file %s: %w", file, err)
+		}
+
+		size += len(content)
+		if size > maxFilesSize {
+			return nil, nil, fmt.Errorf("the files are larger than the 1MiB limit of a configmap")
+		}
+
+		serviceFiles.Data[key] = string(content)
+		fmt.Fprintf(hash, "%s\x00%s\x00", key, content)
+
+		volumes = append(volumes, &Volume{
+			Name:      "file-" + shortHash([]byte(key)),
+			Type:      VolumeTypeConfigMap,
+			MountPath: files[file],
+			ReadOnly:  true,
+			SubPath:   key,
+		})
+	}
+
+	// a change to any of the files renames the configmap which
+	// changes the service checksum and rolls out the service
+	serviceFiles.Name = fmt.Sprintf("%s-files-%s", service, shortHash(hash.Sum(nil)))
+	for _, volume := range volumes {
+		volume.Source = serviceFiles.Name
+	}
+
+	return serviceFiles, volumes, nil
+}
+
+// return the first 10 hex characters of the sha256 of the data
+func shortHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:10]
+}
+
+// convert the mount path into a configmap key, e.g., etc-nginx-nginx.conf
+func configMapKey(mountPath string) string {
+	key := strings.ReplaceAll(strings.Trim(path.Clean(mountPath), "/"), "/", "-")
+	return configMapKeyRegexp.ReplaceAllString(key, "_")
+}
+
+// return the labels of the configmaps of the service
+func (f *ServiceFiles) labels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "nopeus",
+		"nopeus.salfati.group/service": f.Service,
+		"nopeus.salfati.group/files":   "true",
+	}
+}
+
+// create the configmap of the files in the cluster
+func (f *ServiceFiles) Apply(kubeContext string, namespace string) error {
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err != nil {
+		return err
+	}
+
+	return kubernetes.ApplyConfigMap(context.Background(), client, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: f.Name, Namespace: namespace, Labels: f.labels()},
+		Data:       f.Data,
+	})
+}
+
+// remove the configmaps of the previous versions of the files
+// a failure is only logged since it doesn't affect the service
+func (f *ServiceFiles) Prune(kubeContext string, namespace string) {
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err == nil {
+		err = kubernetes.PruneConfigMaps(context.Background(), client, namespace, f.labels(), f.Name)
+	}
+	if err != nil {
+		logger.Debugf("failed to remove the previous files of service %s: %v", f.Service, err)
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadServiceFiles names the configmap after the content of the files
+func TestLoadServiceFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte("worker_processes 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "flags.json"), []byte(`{"beta": false}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"nginx.conf": "/etc/nginx/nginx.conf", "./flags.json": "/app/flags.json"}
+
+	serviceFiles, volumes, err := LoadServiceFiles(dir, "api", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceFiles.Data["etc-nginx-nginx.conf"] != "worker_processes 1;" || serviceFiles.Data["app-flags.json"] != `{"beta": false}` {
+		t.Errorf("expected the files by their mount path, got %v", serviceFiles.Data)
+	}
+	if len(volumes) != 2 {
+		t.Fatalf("expected a volume per file, got %d", len(volumes))
+	}
+	for _, volume := range volumes {
+		if volume.Source != serviceFiles.Name || volume.SubPath == "" || !volume.ReadOnly {
+			t.Errorf("expected a read only mount of the configmap key, got %+v", *volume)
+		}
+	}
+	if err := ValidateVolumes(volumes); err != nil {
+		t.Errorf("expected valid volumes, got %v", err)
+	}
+
+	// the name is stable and changes with the content
+	again, _, err := LoadServiceFiles(dir, "api", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Name != serviceFiles.Name {
+		t.Errorf("expected a stable configmap name, got %s and %s", serviceFiles.Name, again.Name)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "flags.json"), []byte(`{"beta": true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed, _, err := LoadServiceFiles(dir, "api", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Name == serviceFiles.Name {
+		t.Errorf("expected the configmap name to change with the content")
+	}
+}
+
+// TestLoadServiceFilesErrors rejects missing files and conflicting mounts
+func TestLoadServiceFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.conf"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]map[string]string{
+		"missing file":        {"missing.conf": "/etc/missing.conf"},
+		"relative mount path": {"a.conf": "etc/a.conf"},
+		"same mount path":     {"a.conf": "/etc/a.conf", "./a.conf": "/etc/a.conf"},
+	}
+	for name, files := range cases {
+		if _, _, err := LoadServiceFiles(dir, "api", files); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
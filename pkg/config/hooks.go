@@ -0,0 +1,150 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/salfatigroup/nopeus/cli/util"
+	"github.com/salfatigroup/nopeus/helm"
+	"github.com/salfatigroup/nopeus/kubernetes"
+)
+
+// define the supported service hooks
+const (
+	// runs before the new version of the service is rolled out
+	HookPreDeploy = "pre-deploy"
+
+	// runs once the new version of the service is ready
+	HookPostDeploy = "post-deploy"
+)
+
+// the time to wait for a hook to finish by default
+const defaultHookTimeout = 10 * time.Minute
+
+// define the commands that run around the service rollout
+type ServiceHooks struct {
+	// e.g., database migrations
+	PreDeploy *Hook `yaml:"pre_deploy"`
+
+	// e.g., smoke tests or cache warmups
+	PostDeploy *Hook `yaml:"post_deploy"`
+}
+
+// define a command that runs as a kubernetes job
+// with the service image and environment variables
+type Hook struct {
+	// the command to run, e.g., ["npm", "run", "migrate"]
+	Command []string `yaml:"command"`
+
+	// how long to wait for the command to finish, e.g., 5m
+	// defaults to 10m
+	Timeout string `yaml:"timeout"`
+}
+
+// return the hook by its name, nil if it is not defined
+func (h *ServiceHooks) Get(name string) *Hook {
+	if h == nil {
+		return nil
+	}
+
+	switch name {
+	case HookPreDeploy:
+		return h.PreDeploy
+	case HookPostDeploy:
+		return h.PostDeploy
+	default:
+		return nil
+	}
+}
+
+// validate the hooks configs
+func (h *ServiceHooks) Validate() error {
+	for _, name := range []string{HookPreDeploy, HookPostDeploy} {
+		hook := h.Get(name)
+		if hook == nil {
+			continue
+		}
+
+		if len(hook.Command) == 0 {
+			return fmt.Errorf("the %s hook requires a command", name)
+		}
+		if _, err := hook.GetTimeout(); err != nil {
+			return fmt.Errorf("the %s hook: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// return the hook timeout or the default hook timeout
+func (h *Hook) GetTimeout() (time.Duration, error) {
+	if h.Timeout == "" {
+		return defaultHookTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %s - expected a duration, e.g., 5m", h.Timeout)
+	}
+
+	return timeout, nil
+}
+
+// run the hook of the service as a kubernetes job and stream its logs
+// returns an error if the job fails or doesn't finish in time
+func (m *NopeusDefaultMicroservice) RunHook(kubeContext string, name string) error {
+	hook := m.hooks.Get(name)
+	if hook == nil {
+		return nil
+	}
+
+	if m.DryRun {
+		util.Emit(&util.Event{Type: util.EventMessage, Service: m.GetName(), Message: fmt.Sprintf("Dry run mode enabled, skipping the %s hook of service %s", name, m.GetName())})
+		return nil
+	}
+
+	timeout, err := hook.GetTimeout()
+	if err != nil {
+		return err
+	}
+
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err != nil {
+		return err
+	}
+
+	jobName := m.GetName() + "-" + name
+	imagePullSecret, _ := m.Values.Custom["ImagePullSecret"].(string)
+	spec := &kubernetes.JobSpec{
+		Name:            jobName,
+		Namespace:       m.Namespace,
+		Image:           fmt.Sprintf("%s:%s", m.Values.Image, m.Values.Version),
+		Command:         hook.Command,
+		Env:             m.Values.Environment,
+		ImagePullSecret: imagePullSecret,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "nopeus",
+			"nopeus.salfati.group/service": m.GetName(),
+			"nopeus.salfati.group/hook":    name,
+		},
+	}
+	secretEnv, _ := m.Values.Custom["SecretEnvironment"].([]*SecretEnvVar)
+	if len(secretEnv) > 0 {
+		spec.SecretEnv = make(map[string]*kubernetes.SecretKeyRef)
+		for _, envVar := range secretEnv {
+			spec.SecretEnv[envVar.Name] = &kubernetes.SecretKeyRef{Name: envVar.Secret, Key: envVar.Key}
+		}
+	}
+	job := kubernetes.NewJob(spec)
+
+	util.Emit(&util.Event{Type: util.EventMessage, Service: m.GetName(), Message: fmt.Sprintf("Running the %s hook of service %s", name, m.GetName())})
+	err = kubernetes.NewRolloutWatcher(client).RunJob(context.Background(), job, timeout, func(line string) {
+		helm.Logf("[%s] %s", jobName, line)
+	})
+	if err != nil {
+		return fmt.Errorf("the %s hook of service %s failed: %w", name, m.GetName(), err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 )
 
 // define the ingress data for the service
@@ -13,11 +14,20 @@ type Ingress struct {
 	// refer to the backend service
 	ServiceName string `yaml:"service_name"`
 
-	// the port of the backend service
+	// the port of the backend service, used by the paths
+	// that don't target a named port
 	Port int `yaml:"port"`
 
 	// the namespace the upstream resides in
 	Namespace string `yaml:"namespace"`
+
+	// the candidate release that receives a share of the traffic during a
+	// progressive rollout, rendered as the canary upstream of each path
+	CanaryServiceName string `yaml:"-"`
+
+	// the percentage of the path traffic the api gateway sends to the
+	// candidate release, the rest goes to the upstream
+	CanaryWeight int `yaml:"-"`
 }
 
 // define a single ingress path
@@ -30,6 +40,65 @@ type IngressPath struct {
 
 	// the host domains
 	Hosts []string `yaml:"hosts"`
+
+	// the name of the service port the path targets
+	// defaults to the first port of the service
+	Port string `yaml:"port"`
+
+	// the api gateway port a tcp route listens on
+	ListenPort int `yaml:"listen_port"`
+
+	// the resolved port number and protocol of the upstream
+	TargetPort int    `yaml:"-"`
+	Protocol   string `yaml:"-"`
+}
+
+// resolve the upstream port and protocol of each path from the service ports
+func (i *Ingress) ResolvePorts(ports []*Port) error {
+	for idx := range i.Paths {
+		path := &i.Paths[idx]
+
+		port, err := i.getPathPort(path, ports)
+		if err != nil {
+			return err
+		}
+		path.TargetPort = port.Port
+		path.Protocol = port.GetProtocol()
+
+		switch path.Protocol {
+		case ProtocolUDP:
+			return fmt.Errorf("the ingress path %s targets the udp port %s - udp ports can't be routed through the api gateway", path.Path, port.Name)
+		case ProtocolTCP:
+			if path.ListenPort < 1 || path.ListenPort > 65535 {
+				return fmt.Errorf("the ingress route to the tcp port %s requires a listen_port", port.Name)
+			}
+		default:
+			if path.ListenPort != 0 {
+				return fmt.Errorf("the ingress path %s targets the %s port %s - listen_port is only supported by tcp ports", path.Path, path.Protocol, port.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// return the service port the path targets
+func (i *Ingress) getPathPort(path *IngressPath, ports []*Port) (*Port, error) {
+	if path.Port != "" {
+		for _, port := range ports {
+			if port.Name == path.Port {
+				return port, nil
+			}
+		}
+
+		return nil, fmt.Errorf("the ingress path %s targets the unknown port %s", path.Path, path.Port)
+	}
+
+	if i.Port != 0 || len(ports) == 0 {
+		return &Port{Name: defaultPortName, Port: i.Port, Protocol: ProtocolHTTP}, nil
+	}
+
+	return ports[0], nil
 }
 
 // create ingress service data
@@ -39,9 +108,27 @@ func NewIngressTemplateData(cfg *NopeusConfig, ingressList []*Ingress, env strin
 		return &NopeusDefaultMicroservice{}, err
 	}
 
+	// the api gateway listens on an extra port for each tcp route
+	streamPorts := []int{}
+	listening := make(map[int]string)
+	for _, ingress := range ingressList {
+		for _, path := range ingress.Paths {
+			if path.ListenPort == 0 {
+				continue
+			}
+			if service, ok := listening[path.ListenPort]; ok {
+				return &NopeusDefaultMicroservice{}, fmt.Errorf("the services %s and %s both listen on the api gateway port %d", service, ingress.ServiceName, path.ListenPort)
+			}
+			listening[path.ListenPort] = ingress.ServiceName
+			streamPorts = append(streamPorts, path.ListenPort)
+		}
+	}
+	sort.Ints(streamPorts)
+
 	custom := map[string]interface{}{
-		"Ingress":    ingressList,
-		"HostPrefix": "",
+		"Ingress":     ingressList,
+		"HostPrefix":  "",
+		"StreamPorts": streamPorts,
 	}
 
 	if env != "prod" {
@@ -63,5 +150,7 @@ func NewIngressTemplateData(cfg *NopeusConfig, ingressList []*Ingress, env strin
 			Version: "latest",
 			Custom:  custom,
 		},
+		// applied before the services it routes to
+		infrastructure: true,
 	}, nil
 }
@@ -9,5 +9,13 @@ func init() {
 	gob.Register([]*IngressPath{})
 	gob.Register([]*Ingress{})
 	gob.Register(&NopeusDefaultMicroservice{})
+	gob.Register(&NopeusWorkerMicroservice{})
+	gob.Register(&NopeusCronJobMicroservice{})
+	gob.Register(&Resources{})
+	gob.Register(&Autoscaling{})
+	gob.Register(&Probes{})
+	gob.Register([]*Port{})
+	gob.Register([]*Volume{})
+	gob.Register([]*SecretEnvVar{})
 	gob.Register(map[string]string{})
 }
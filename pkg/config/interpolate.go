@@ -0,0 +1,279 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/salfatigroup/nopeus/logger"
+)
+
+// the names of the environment variables that can be referenced
+var envVarNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// define the values the ${...} references of the env values resolve from
+//
+//	${NAME}                 the NAME environment variable
+//	${NAME:-default}        default when NAME is unset or empty
+//	${NAME:?message}        fail with the message when NAME is unset or empty
+//	${environment.name}     the name of the deployed environment
+//	${services.api.url}     the url, host or port of another service
+//	${outputs.region}       a terraform output of the environment
+//	$${NAME}                the literal ${NAME}
+type Interpolation struct {
+	// the name of the deployed environment
+	Environment string
+
+	// the namespace the services are deployed to
+	Namespace string
+
+	// the services that can be referenced by name
+	Services map[string]*Service
+
+	// the terraform outputs of the environment. references to the
+	// outputs are kept as is until terraform has run and set them
+	Outputs map[string]string
+}
+
+// define the references that could not be resolved, reported together
+type UnresolvedReferencesError struct {
+	// the description of each unresolved reference
+	References []string
+}
+
+func (e *UnresolvedReferencesError) Error() string {
+	return fmt.Sprintf("unresolved references:\n  - %s", strings.Join(e.References, "\n  - "))
+}
+
+// append the unresolved references of the error to the list, prefixed
+// with the location of the value. other errors are returned as is
+func collectUnresolved(references []string, location string, err error) ([]string, error) {
+	unresolved, ok := err.(*UnresolvedReferencesError)
+	if !ok {
+		return references, err
+	}
+
+	for _, reference := range unresolved.References {
+		references = append(references, fmt.Sprintf("%s: %s", location, reference))
+	}
+	return references, nil
+}
+
+// replace the ${...} references in the value. every unresolved
+// reference is reported in a single UnresolvedReferencesError
+func (i *Interpolation) Interpolate(value string) (string, error) {
+	var result strings.Builder
+	unresolved := []string{}
+
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			result.WriteString(value)
+			break
+		}
+
+		// $${ escapes the reference
+		if start > 0 && value[start-1] == '$' {
+			result.WriteString(value[:start-1])
+			result.WriteString("${")
+			value = value[start+2:]
+			continue
+		}
+
+		result.WriteString(value[:start])
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			unresolved = append(unresolved, fmt.Sprintf("unterminated reference %s", value[start:]))
+			break
+		}
+
+		reference := value[start : start+end+1]
+		if i.Outputs == nil && strings.HasPrefix(reference, "${outputs.") {
+			result.WriteString(reference)
+			value = value[start+end+1:]
+			continue
+		}
+
+		resolved, err := i.resolve(reference[2 : len(reference)-1])
+		if err != nil {
+			unresolved = append(unresolved, err.Error())
+		} else {
+			result.WriteString(resolved)
+		}
+		value = value[start+end+1:]
+	}
+
+	if len(unresolved) > 0 {
+		return "", &UnresolvedReferencesError{References: unresolved}
+	}
+	return result.String(), nil
+}
+
+// return true if the value references a terraform output that is not set yet
+func (i *Interpolation) hasDeferredReferences(value string) bool {
+	return i.Outputs == nil && strings.Contains(value, "${outputs.")
+}
+
+// interpolate the value and resolve the references to the registered
+// secret stores, e.g., vault://kv/${environment.name}/api#key
+func (i *Interpolation) parse(value string) (string, error) {
+	interpolated, err := i.Interpolate(value)
+	if err != nil || i.hasDeferredReferences(value) {
+		return interpolated, err
+	}
+
+	if resolved, ok, err := resolveSecretReference(interpolated); ok || err != nil {
+		return resolved, err
+	}
+	return interpolated, nil
+}
+
+// resolve a single reference, e.g., NAME:-default
+func (i *Interpolation) resolve(expression string) (string, error) {
+	name, fallback, operator := expression, "", ""
+	for _, op := range []string{":-", ":?"} {
+		if index := strings.Index(expression, op); index >= 0 {
+			name, fallback, operator = expression[:index], expression[index+len(op):], op
+			break
+		}
+	}
+
+	value, err := i.lookup(name)
+	if err == nil && value != "" {
+		return value, nil
+	}
+
+	switch operator {
+	case ":-":
+		return fallback, nil
+	case ":?":
+		return "", fmt.Errorf("%s: %s", name, fallback)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s is empty", name)
+}
+
+// return the value the name references
+func (i *Interpolation) lookup(name string) (string, error) {
+	parts := strings.Split(name, ".")
+	switch {
+	case parts[0] == "environment" && len(parts) == 2 && parts[1] == "name":
+		return i.Environment, nil
+
+	case parts[0] == "services" && len(parts) == 3:
+		return i.lookupService(parts[1], parts[2])
+
+	case parts[0] == "outputs" && len(parts) == 2:
+		value, ok := i.Outputs[parts[1]]
+		if !ok {
+			return "", fmt.Errorf("terraform output %s is not defined", parts[1])
+		}
+		return value, nil
+
+	case len(parts) == 1 && envVarNameRegexp.MatchString(name):
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		// values sourced from the environment are masked in the logs
+		logger.RegisterSensitive(value)
+		return value, nil
+	}
+
+	return "", fmt.Errorf("invalid reference %s - expected NAME, environment.name, services.<name>.<url|host|port> or outputs.<name>", name)
+}
+
+// return the url, host or port of the service
+func (i *Interpolation) lookupService(name string, field string) (string, error) {
+	service, ok := i.Services[name]
+	if !ok {
+		return "", fmt.Errorf("unknown service %s", name)
+	}
+
+	host := serviceHost(name, i.Namespace)
+	if field == "host" {
+		return host, nil
+	}
+
+	port, err := i.servicePort(service)
+	if err != nil {
+		return "", fmt.Errorf("service %s: %w", name, err)
+	}
+
+	switch field {
+	case "port":
+		return strconv.Itoa(port.Port), nil
+	case "url":
+		return fmt.Sprintf("%s://%s:%d", port.GetProtocol(), host, port.Port), nil
+	}
+
+	return "", fmt.Errorf("unknown service field %s - expected url, host or port", field)
+}
+
+// return the first port of the service, falls back to its PORT variable
+func (i *Interpolation) servicePort(service *Service) (*Port, error) {
+	if len(service.Ports) > 0 {
+		return service.Ports[0], nil
+	}
+
+	raw, ok := service.GetRawEnvironmentVariables()["PORT"]
+	if !ok {
+		return nil, fmt.Errorf("no ports are defined")
+	}
+
+	// the port can't reference other services
+	value, err := (&Interpolation{Environment: i.Environment, Outputs: i.Outputs}).Interpolate(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PORT: %w", err)
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PORT %s - expected a number", value)
+	}
+
+	return &Port{Name: defaultPortName, Port: port, Protocol: ProtocolHTTP}, nil
+}
+
+// interpolate the env values of all the services of the environment
+// and report every unresolved reference at once
+func (c *CloudApplicationLayerConfig) ParseEnvironmentVariables(envName string, namespace string, outputs map[string]string) error {
+	services, err := c.GetServices()
+	if err != nil {
+		return err
+	}
+
+	interpolation := &Interpolation{
+		Environment: envName,
+		Namespace:   namespace,
+		Services:    services,
+		Outputs:     outputs,
+	}
+
+	names := []string{}
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	unresolved := []string{}
+	for _, name := range names {
+		err := services[name].InterpolateEnvironmentVariables(interpolation)
+		if references, ok := err.(*UnresolvedReferencesError); ok {
+			for _, reference := range references.References {
+				unresolved = append(unresolved, fmt.Sprintf("services.%s.%s", name, reference))
+			}
+		} else if err != nil {
+			return fmt.Errorf("service %s: %w", name, err)
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return &UnresolvedReferencesError{References: unresolved}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestInterpolate replaces the references inside the values
+func TestInterpolate(t *testing.T) {
+	t.Setenv("TEST_INTERPOLATE_USER", "admin")
+	t.Setenv("TEST_INTERPOLATE_EMPTY", "")
+	interpolation := &Interpolation{
+		Environment: "staging",
+		Namespace:   "nopeus-app",
+		Services: map[string]*Service{
+			"api":   {Ports: []*Port{{Name: "grpc", Port: 9090, Protocol: ProtocolGRPC}}},
+			"web":   {EnvironmentVariables: EnvironmentVariables{"PORT": "${TEST_INTERPOLATE_PORT:-8080}"}},
+			"cache": {},
+		},
+		Outputs: map[string]string{"region": "us-east-1"},
+	}
+
+	tests := map[string]string{
+		"plain":                                     "plain",
+		"postgres://${TEST_INTERPOLATE_USER}@db":    "postgres://admin@db",
+		"${TEST_INTERPOLATE_MISSING:-fallback}":     "fallback",
+		"${TEST_INTERPOLATE_EMPTY:-fallback}":       "fallback",
+		"${TEST_INTERPOLATE_USER:-fallback}":        "admin",
+		"app-${environment.name}":                   "app-staging",
+		"${services.api.url}":                       "grpc://api.nopeus-app.svc.cluster.local:9090",
+		"${services.web.url}/v1":                    "http://web.nopeus-app.svc.cluster.local:8080/v1",
+		"${services.web.host}:${services.web.port}": "web.nopeus-app.svc.cluster.local:8080",
+		"${outputs.region}":                         "us-east-1",
+		"$${TEST_INTERPOLATE_USER}":                 "${TEST_INTERPOLATE_USER}",
+	}
+	for value, expected := range tests {
+		interpolated, err := interpolation.Interpolate(value)
+		if err != nil {
+			t.Errorf("failed to interpolate %s: %v", value, err)
+			continue
+		}
+		if interpolated != expected {
+			t.Errorf("expected %s for %s, got %s", expected, value, interpolated)
+		}
+	}
+}
+
+// TestInterpolateUnresolved reports every unresolved reference of the value
+func TestInterpolateUnresolved(t *testing.T) {
+	interpolation := &Interpolation{Environment: "staging", Services: map[string]*Service{"cache": {}}, Outputs: map[string]string{}}
+
+	_, err := interpolation.Interpolate("${TEST_INTERPOLATE_MISSING} ${TEST_INTERPOLATE_MISSING:?set the token} ${services.missing.url} ${services.cache.port} ${outputs.missing} ${invalid-name} ${unterminated")
+	unresolved, ok := err.(*UnresolvedReferencesError)
+	if !ok {
+		t.Fatalf("expected an unresolved references error, got %v", err)
+	}
+
+	expected := []string{
+		"environment variable TEST_INTERPOLATE_MISSING is not set",
+		"TEST_INTERPOLATE_MISSING: set the token",
+		"unknown service missing",
+		"service cache: no ports are defined",
+		"terraform output missing is not defined",
+		"invalid reference invalid-name",
+		"unterminated reference ${unterminated",
+	}
+	if len(unresolved.References) != len(expected) {
+		t.Fatalf("expected %d unresolved references, got %v", len(expected), unresolved.References)
+	}
+	for index, reference := range unresolved.References {
+		if !strings.HasPrefix(reference, expected[index]) {
+			t.Errorf("expected %q, got %q", expected[index], reference)
+		}
+	}
+}
+
+// TestInterpolateDeferredOutputs keeps the output references until terraform has run
+func TestInterpolateDeferredOutputs(t *testing.T) {
+	service := &Service{
+		EnvironmentVariables: EnvironmentVariables{"BUCKET": "s3://${outputs.bucket}/${environment.name}"},
+	}
+	if err := service.InterpolateEnvironmentVariables(&Interpolation{Environment: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	env := service.GetEnvironmentVariables("prod")
+	if env["BUCKET"] != "s3://${outputs.bucket}/prod" {
+		t.Errorf("expected the output reference to be kept, got %s", env["BUCKET"])
+	}
+
+	// the parsed variables are updated in place once the outputs are set
+	if err := service.InterpolateEnvironmentVariables(&Interpolation{Environment: "prod", Outputs: map[string]string{"bucket": "assets"}}); err != nil {
+		t.Fatal(err)
+	}
+	if env["BUCKET"] != "s3://assets/prod" {
+		t.Errorf("expected the output to resolve, got %s", env["BUCKET"])
+	}
+
+	// secrets are created before terraform runs
+	service.Secrets = map[string]string{"TOKEN": "${outputs.token}"}
+	if err := service.InterpolateEnvironmentVariables(&Interpolation{Environment: "prod"}); err == nil {
+		t.Errorf("expected an error for a secret referencing a terraform output")
+	}
+}
+
+// TestParseEnvironmentVariablesAllServices reports the unresolved references of all the services
+func TestParseEnvironmentVariablesAllServices(t *testing.T) {
+	cal := &CloudApplicationLayerConfig{
+		Services: map[string]*Service{
+			"api": {
+				EnvironmentVariables: EnvironmentVariables{"PORT": "8080", "DB_USER": "${TEST_INTERPOLATE_MISSING_USER}"},
+				Secrets:              map[string]string{"DB_PASSWORD": "${TEST_INTERPOLATE_MISSING_PASSWORD}"},
+			},
+			"web": {
+				EnvironmentVariables: EnvironmentVariables{"API_URL": "${services.api.url}", "CDN": "${services.cdn.url}"},
+			},
+		},
+	}
+
+	err := cal.ParseEnvironmentVariables("prod", "nopeus-app", nil)
+	unresolved, ok := err.(*UnresolvedReferencesError)
+	if !ok {
+		t.Fatalf("expected an unresolved references error, got %v", err)
+	}
+
+	expected := []string{
+		"services.api.environment.DB_USER: environment variable TEST_INTERPOLATE_MISSING_USER is not set",
+		"services.api.secrets.DB_PASSWORD: environment variable TEST_INTERPOLATE_MISSING_PASSWORD is not set",
+		"services.web.environment.CDN: unknown service cdn",
+	}
+	if strings.Join(unresolved.References, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected the references of all the services, got %v", unresolved.References)
+	}
+	if url := cal.Services["web"].GetEnvironmentVariables("prod")["API_URL"]; url != "http://api.nopeus-app.svc.cluster.local:8080" {
+		t.Errorf("expected the url of the api service, got %s", url)
+	}
+}
@@ -1,18 +1,19 @@
 package config
 
 import (
-	"bytes"
 	"context"
 	"crypto/md5"
-	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	helmclient "github.com/mittwald/go-helm-client"
 	"github.com/salfatigroup/nopeus/cli/util"
 	"github.com/salfatigroup/nopeus/helm"
 	"github.com/salfatigroup/nopeus/logger"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 // define the runtime default microservice template data
@@ -24,6 +25,40 @@ type NopeusDefaultMicroservice struct {
 	Values         *HelmRendererValues `yaml:"values"`
 	Namespace      string              `yaml:"namespace"`
 	DryRun         bool                `yaml:"-"`
+
+	// how long to wait for the release to become ready
+	// kept out of the checksum to avoid needless rollouts
+	rolloutTimeout time.Duration
+
+	// roll back to the previous release when the rollout fails
+	atomic bool
+
+	// define how a new version is rolled out
+	strategy *Strategy
+
+	// the ingress the traffic of the service is routed through
+	ingress *Ingress
+
+	// the commands that run around the rollout
+	hooks *ServiceHooks
+
+	// the configmap of the files mounted into the service
+	files *ServiceFiles
+
+	// the secret of the secret variables of the service
+	// kept out of the checksum, only its name is rendered
+	secrets *ServiceSecrets
+
+	// the deployment or stateful set running a long running service
+	// checked against the cluster before the release is applied
+	workload *workloadSpec
+
+	// the services that must be ready before this one
+	dependsOn []string
+
+	// the service is part of the infrastructure the services rely on,
+	// e.g., the api gateway, and is applied before them
+	infrastructure bool
 }
 
 // return the name of the service
@@ -70,11 +105,19 @@ func (m *NopeusDefaultMicroservice) GetChartSpec() (*helmclient.ChartSpec, error
 		// Version:          "latest",
 		ValuesYaml:       valuesYaml,
 		DryRun:           m.DryRun,
-		Wait:             false, // true, TODO: wait for jobs
+		Wait:             false, // the rollout is followed by ApplyHelmChart
 		DependencyUpdate: true,
 		Timeout:          time.Duration(time.Minute * 15),
 	}
 
+	// let helm roll back failed upgrades, helm waits
+	// for the release when installing atomically
+	if m.atomic {
+		chartSpec.Atomic = true
+		chartSpec.CleanupOnFail = true
+		chartSpec.Timeout = m.GetRolloutTimeout()
+	}
+
 	if m.Namespace != "" {
 		chartSpec.Namespace = m.Namespace
 		chartSpec.CreateNamespace = true
@@ -83,9 +126,58 @@ func (m *NopeusDefaultMicroservice) GetChartSpec() (*helmclient.ChartSpec, error
 	return &chartSpec, nil
 }
 
-// apply the given chart to the cluster
+// run the pre-deploy hook, apply the given chart to
+// the cluster and run the post-deploy hook once it's ready
 func (m *NopeusDefaultMicroservice) ApplyHelmChart(kubeContext string) error {
-	fmt.Println(util.GrayText("Applying helm chart for service " + m.GetName()))
+	if err := m.PrepareRelease(kubeContext); err != nil {
+		return err
+	}
+
+	if err := m.RunHook(kubeContext, HookPreDeploy); err != nil {
+		return err
+	}
+
+	if err := m.ApplyRelease(kubeContext); err != nil {
+		return err
+	}
+
+	return m.RunHook(kubeContext, HookPostDeploy)
+}
+
+// check the workload in the cluster can be upgraded and create the
+// configmap and secret of the service. runs before the pre-deploy
+// hook since the hook reads the same files and secrets as the pods
+func (m *NopeusDefaultMicroservice) PrepareRelease(kubeContext string) error {
+	if m.DryRun {
+		return nil
+	}
+
+	// helm would replace the workload or fail on a claim template change
+	if m.workload != nil {
+		if err := m.workload.check(kubeContext, m.Namespace, m.Name); err != nil {
+			return fmt.Errorf("service %s: %w", m.Name, err)
+		}
+	}
+
+	// the pods and hooks can't start before their configmap and secret exist
+	if m.files != nil {
+		if err := m.files.Apply(kubeContext, m.Namespace); err != nil {
+			return err
+		}
+	}
+	if m.secrets != nil {
+		if err := m.secrets.Apply(kubeContext, m.Namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply the given chart to the cluster without running the hooks,
+// expects the release to be prepared
+func (m *NopeusDefaultMicroservice) ApplyRelease(kubeContext string) error {
+	util.Emit(&util.Event{Type: util.EventMessage, Service: m.GetName(), Message: "Applying helm chart for service " + m.GetName()})
 	// get chart specifications
 	chartSpec, err := m.GetChartSpec()
 	if err != nil {
@@ -102,13 +194,87 @@ func (m *NopeusDefaultMicroservice) ApplyHelmChart(kubeContext string) error {
 	// install the chart
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Minute*15))
 	defer cancel()
-	if _, err := helmClient.Client.InstallOrUpgradeChart(ctx, chartSpec, nil); err != nil {
+	release, err := helmClient.Client.InstallOrUpgradeChart(ctx, chartSpec, nil)
+	if err != nil {
 		return err
 	}
 
+	// wait for the service pods to become ready
+	if m.DryRun {
+		return nil
+	}
+	util.Emit(&util.Event{Type: util.EventMessage, Service: m.GetName(), Message: "Waiting for service " + m.GetName() + " to become ready"})
+	if err := helm.WaitForRollout(release, kubeContext, m.GetRolloutTimeout()); err != nil {
+		return m.rollbackFailedRelease(helmClient, release, chartSpec, err)
+	}
+
+	// the previous files and secrets are no longer in use once the new pods are
+	// ready. the candidate of a progressive rollout runs next to the previous pods
+	if m.files != nil && m.files.Service == m.Name {
+		m.files.Prune(kubeContext, m.Namespace)
+	}
+	if m.secrets != nil && m.secrets.Service == m.Name {
+		m.secrets.Prune(kubeContext, m.Namespace)
+	}
+
 	return nil
 }
 
+// roll an atomic service back once its release failed to become ready,
+// returns the rollout error of the release
+func (m *NopeusDefaultMicroservice) rollbackFailedRelease(helmClient *helm.HelmClient, rel *release.Release, chartSpec *helmclient.ChartSpec, err error) error {
+	if !m.atomic {
+		return err
+	}
+
+	util.Emit(&util.Event{Type: util.EventMessage, Service: m.GetName(), Message: "Rolling back service " + m.GetName() + " to the previous release"})
+	if rollbackErr := helmClient.RollbackRelease(rel, chartSpec); rollbackErr != nil {
+		return fmt.Errorf("%w\nfailed to roll back service %s: %v", err, m.GetName(), rollbackErr)
+	}
+
+	return fmt.Errorf("%w\nservice %s was rolled back to the previous release", err, m.GetName())
+}
+
+// return the time to wait for the release to become ready
+func (m *NopeusDefaultMicroservice) GetRolloutTimeout() time.Duration {
+	if m.rolloutTimeout == 0 {
+		return DefaultRolloutTimeout
+	}
+
+	return m.rolloutTimeout
+}
+
+// return the rollout strategy of the service
+func (m *NopeusDefaultMicroservice) GetStrategy() *Strategy {
+	return m.strategy
+}
+
+// return the ingress of the service, nil if the service is private
+func (m *NopeusDefaultMicroservice) GetIngress() *Ingress {
+	return m.ingress
+}
+
+// return the candidate release that runs the new version next to the
+// current one during a progressive rollout
+func (m *NopeusDefaultMicroservice) GetCandidate() *NopeusDefaultMicroservice {
+	name := m.Name + "-canary"
+	values := *m.Values
+	values.Name = name
+
+	return &NopeusDefaultMicroservice{
+		Name:           name,
+		HelmPackage:    m.HelmPackage,
+		ValuesTemplate: m.ValuesTemplate,
+		ValuesPath:     filepath.Join(filepath.Dir(m.ValuesPath), name+".values.yaml"),
+		Values:         &values,
+		Namespace:      m.Namespace,
+		DryRun:         m.DryRun,
+		rolloutTimeout: m.rolloutTimeout,
+		files:          m.files,
+		secrets:        m.secrets,
+	}
+}
+
 // delete the given chart from the cluster
 func (m *NopeusDefaultMicroservice) DeleteHelmChart(kubeContext string) error {
 	logger.Debugf("removing helm chart for service %s", m.GetName())
@@ -129,12 +295,54 @@ func (m *NopeusDefaultMicroservice) DeleteHelmChart(kubeContext string) error {
 	return helmClient.Client.UninstallRelease(chartSpec)
 }
 
+// return the services that must be ready before this one
+func (m *NopeusDefaultMicroservice) GetDependencies() []string {
+	return m.dependsOn
+}
+
+// set the services that must be ready before this one
+func (m *NopeusDefaultMicroservice) SetDependencies(dependsOn []string) {
+	m.dependsOn = dependsOn
+}
+
+// return true if the service is part of the infrastructure
+func (m *NopeusDefaultMicroservice) IsInfrastructure() bool {
+	return m.infrastructure
+}
+
+// mark the service as part of the infrastructure the services rely on
+func (m *NopeusDefaultMicroservice) SetInfrastructure(infrastructure bool) {
+	m.infrastructure = infrastructure
+}
+
+// define the configs the checksum of a service covers. the runtime
+// configs are listed since only the exported fields are encoded
+type microserviceChecksum struct {
+	*NopeusDefaultMicroservice
+	Atomic    bool
+	Strategy  *Strategy
+	Hooks     *ServiceHooks
+	DependsOn []string
+}
+
 // return the checksum of the service
 func (m *NopeusDefaultMicroservice) GetChecksum() (string, error) {
-	var b bytes.Buffer
-	if err := gob.NewEncoder(&b).Encode(m); err != nil {
+	return checksum(&microserviceChecksum{
+		NopeusDefaultMicroservice: m,
+		Atomic:                    m.atomic,
+		Strategy:                  m.strategy,
+		Hooks:                     m.hooks,
+		DependsOn:                 m.dependsOn,
+	})
+}
+
+// return the md5 checksum of the json encoding of the value. unlike gob
+// the json encoding sorts the map keys so equal values get equal checksums
+func checksum(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
 		return "", err
 	}
-	md5sum := md5.Sum(b.Bytes())
-	return fmt.Sprintf("%x", md5sum), nil
+
+	return fmt.Sprintf("%x", md5.Sum(encoded)), nil
 }
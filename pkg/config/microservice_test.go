@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	"github.com/salfatigroup/nopeus/helm"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// a helm client recording the release operations
+type fakeHelmClient struct {
+	helmclient.Client
+	ops []string
+	err error
+}
+
+func (c *fakeHelmClient) RollbackRelease(spec *helmclient.ChartSpec) error {
+	c.ops = append(c.ops, "rollback "+spec.ReleaseName)
+	return c.err
+}
+
+func (c *fakeHelmClient) UninstallRelease(spec *helmclient.ChartSpec) error {
+	c.ops = append(c.ops, "uninstall "+spec.ReleaseName)
+	return c.err
+}
+
+// TestRollbackFailedRelease restores the previous state of an atomic service
+func TestRollbackFailedRelease(t *testing.T) {
+	rolloutErr := fmt.Errorf("pod api-0 is crashing")
+	for name, test := range map[string]struct {
+		atomic   bool
+		version  int
+		fails    error
+		expected []string
+		message  string
+	}{
+		"not atomic":      {atomic: false, version: 2, expected: nil, message: "pod api-0 is crashing"},
+		"first release":   {atomic: true, version: 1, expected: []string{"uninstall api"}, message: "was rolled back"},
+		"upgrade":         {atomic: true, version: 3, expected: []string{"rollback api"}, message: "was rolled back"},
+		"failed rollback": {atomic: true, version: 3, fails: fmt.Errorf("timeout"), expected: []string{"rollback api"}, message: "failed to roll back service api: timeout"},
+	} {
+		client := &fakeHelmClient{err: test.fails}
+		microservice := &NopeusDefaultMicroservice{Name: "api", atomic: test.atomic}
+
+		err := microservice.rollbackFailedRelease(&helm.HelmClient{Client: client}, &release.Release{Name: "api", Version: test.version}, &helmclient.ChartSpec{ReleaseName: "api"}, rolloutErr)
+		if err == nil || !strings.Contains(err.Error(), test.message) || !strings.Contains(err.Error(), rolloutErr.Error()) {
+			t.Errorf("%s: expected an error with %q, got %v", name, test.message, err)
+		}
+		if !reflect.DeepEqual(client.ops, test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, client.ops)
+		}
+	}
+}
+
+// create a service with many variables to vary the map iteration order
+func newChecksumMicroservice() *NopeusDefaultMicroservice {
+	environment := map[string]string{}
+	for i := 0; i < 32; i++ {
+		environment[fmt.Sprintf("VAR_%d", i)] = fmt.Sprintf("value-%d", i)
+	}
+
+	return &NopeusDefaultMicroservice{
+		Name:   "api",
+		Values: &HelmRendererValues{Name: "api", Environment: environment, Custom: map[string]interface{}{"Replicas": 2, "HealthCheckURL": "/health"}},
+	}
+}
+
+// TestGetChecksumStable returns the same checksum for equal services
+func TestGetChecksumStable(t *testing.T) {
+	expected, err := newChecksumMicroservice().GetChecksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		checksum, err := newChecksumMicroservice().GetChecksum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if checksum != expected {
+			t.Fatalf("expected the checksum %s, got %s", expected, checksum)
+		}
+	}
+}
+
+// TestGetChecksumRuntimeConfigs changes the checksum with the configs that aren't rendered
+func TestGetChecksumRuntimeConfigs(t *testing.T) {
+	base, err := newChecksumMicroservice().GetChecksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, change := range map[string]func(m *NopeusDefaultMicroservice){
+		"atomic":     func(m *NopeusDefaultMicroservice) { m.atomic = true },
+		"strategy":   func(m *NopeusDefaultMicroservice) { m.strategy = &Strategy{Type: StrategyCanary} },
+		"hooks":      func(m *NopeusDefaultMicroservice) { m.hooks = &ServiceHooks{PreDeploy: &Hook{}} },
+		"depends on": func(m *NopeusDefaultMicroservice) { m.dependsOn = []string{"main"} },
+	} {
+		microservice := newChecksumMicroservice()
+		change(microservice)
+
+		checksum, err := microservice.GetChecksum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if checksum == base {
+			t.Errorf("%s: expected the checksum to change", name)
+		}
+	}
+
+	// the rollout timeout only changes how long the release is waited for
+	microservice := newChecksumMicroservice()
+	microservice.rolloutTimeout = DefaultRolloutTimeout * 2
+	if checksum, _ := microservice.GetChecksum(); checksum != base {
+		t.Errorf("expected the rollout timeout to be kept out of the checksum")
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// define the supported port protocols
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+)
+
+// the name of the port created from the PORT environment variable
+const defaultPortName = "http"
+
+// kubernetes port names are lowercase dns labels of up to 15 characters
+var portNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,13}[a-z0-9])?$`)
+
+// define a port the service container listens on
+type Port struct {
+	// the port name referenced by the ingress paths and probes, e.g., http
+	Name string `yaml:"name"`
+
+	// the container port number
+	Port int `yaml:"port"`
+
+	// the application protocol - http, grpc, tcp or udp
+	// defaults to http
+	Protocol string `yaml:"protocol"`
+}
+
+// return the application protocol of the port, defaults to http
+func (p *Port) GetProtocol() string {
+	if p.Protocol == "" {
+		return ProtocolHTTP
+	}
+
+	return p.Protocol
+}
+
+// return the kubernetes transport protocol of the port - TCP or UDP
+func (p *Port) GetTransport() string {
+	if p.GetProtocol() == ProtocolUDP {
+		return "UDP"
+	}
+
+	return "TCP"
+}
+
+// validate the ports have unique valid names, numbers and protocols
+func ValidatePorts(ports []*Port) error {
+	names := make(map[string]bool)
+	for _, port := range ports {
+		if !portNameRegexp.MatchString(port.Name) {
+			return fmt.Errorf("invalid port name %q - expected up to 15 lowercase letters, numbers and dashes", port.Name)
+		}
+		if names[port.Name] {
+			return fmt.Errorf("the port %s is defined more than once", port.Name)
+		}
+		names[port.Name] = true
+
+		if port.Port < 1 || port.Port > 65535 {
+			return fmt.Errorf("invalid port %s number %d - expected 1-65535", port.Name, port.Port)
+		}
+
+		switch port.GetProtocol() {
+		case ProtocolHTTP, ProtocolGRPC, ProtocolTCP, ProtocolUDP:
+		default:
+			return fmt.Errorf("invalid port %s protocol %s - expected %s, %s, %s or %s", port.Name, port.Protocol, ProtocolHTTP, ProtocolGRPC, ProtocolTCP, ProtocolUDP)
+		}
+	}
+
+	return nil
+}
+
+// return the ports of the service. a service without ports listens
+// on a single http port set by the PORT environment variable if any
+func (s *Service) GetPorts(envName string) ([]*Port, error) {
+	if len(s.Ports) > 0 {
+		return s.Ports, nil
+	}
+
+	value := s.GetEnvironmentVariables(envName)["PORT"]
+	if value == "" {
+		return nil, nil
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PORT %s - expected a number", value)
+	}
+
+	return []*Port{{Name: defaultPortName, Port: port, Protocol: ProtocolHTTP}}, nil
+}
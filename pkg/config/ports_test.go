@@ -0,0 +1,73 @@
+package config
+
+import "testing"
+
+// TestValidatePorts rejects invalid and duplicated ports
+func TestValidatePorts(t *testing.T) {
+	valid := []*Port{{Name: "http", Port: 8080}, {Name: "grpc", Port: 9000, Protocol: ProtocolGRPC}, {Name: "dns", Port: 53, Protocol: ProtocolUDP}}
+	if err := ValidatePorts(valid); err != nil {
+		t.Errorf("expected valid ports, got %v", err)
+	}
+
+	cases := map[string][]*Port{
+		"missing name":   {{Port: 80}},
+		"invalid name":   {{Name: "HTTP_PORT", Port: 80}},
+		"duplicate name": {{Name: "http", Port: 80}, {Name: "http", Port: 81}},
+		"out of range":   {{Name: "http", Port: 70000}},
+		"unknown proto":  {{Name: "http", Port: 80, Protocol: "sctp"}},
+	}
+	for name, ports := range cases {
+		if err := ValidatePorts(ports); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestGetPortsFromEnvironment reads the PORT variable once it is parsed
+func TestGetPortsFromEnvironment(t *testing.T) {
+	t.Setenv("API_PORT", "3000")
+	service := &Service{EnvironmentVariables: map[string]string{"PORT": "${API_PORT}"}}
+	if err := service.ParseEnvironmentVariables("prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	ports, err := service.GetPorts("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 1 || ports[0].Port != 3000 || ports[0].Name != "http" {
+		t.Errorf("expected the http port 3000, got %+v", ports)
+	}
+}
+
+// TestIngressResolvePorts routes each path to its named port
+func TestIngressResolvePorts(t *testing.T) {
+	ports := []*Port{{Name: "http", Port: 8080}, {Name: "grpc", Port: 9000, Protocol: ProtocolGRPC}, {Name: "db", Port: 5432, Protocol: ProtocolTCP}}
+	ingress := &Ingress{Paths: []IngressPath{{Path: "/"}, {Path: "/api.Users/", Port: "grpc"}, {Port: "db", ListenPort: 15432}}}
+	if err := ingress.ResolvePorts(ports); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		port     int
+		protocol string
+	}{{8080, ProtocolHTTP}, {9000, ProtocolGRPC}, {5432, ProtocolTCP}}
+	for i, path := range ingress.Paths {
+		if path.TargetPort != expected[i].port || path.Protocol != expected[i].protocol {
+			t.Errorf("expected path %d to target %d/%s, got %d/%s", i, expected[i].port, expected[i].protocol, path.TargetPort, path.Protocol)
+		}
+	}
+
+	cases := map[string]IngressPath{
+		"unknown port":          {Path: "/", Port: "metrics"},
+		"tcp without listen":    {Port: "db"},
+		"http with listen port": {Path: "/", ListenPort: 8000},
+		"udp":                   {Port: "dns"},
+	}
+	ports = append(ports, &Port{Name: "dns", Port: 53, Protocol: ProtocolUDP})
+	for name, path := range cases {
+		if err := (&Ingress{Paths: []IngressPath{path}}).ResolvePorts(ports); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
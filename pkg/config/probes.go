@@ -0,0 +1,262 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// the container port the probes target by default
+const defaultProbePort = "http"
+
+// define the health checks of the service containers
+type Probes struct {
+	// restart the container when the check fails
+	// defaults to an http check of the health_url
+	Liveness *Probe `yaml:"liveness"`
+
+	// stop sending traffic to the container while the check fails
+	// defaults to an http check of the health_url
+	Readiness *Probe `yaml:"readiness"`
+
+	// hold the other checks until the container has started
+	Startup *Probe `yaml:"startup"`
+}
+
+// define a single health check - exactly one of http, tcp, grpc or exec
+type Probe struct {
+	// send an http get request, succeeds on a 2xx or 3xx response
+	HTTP *HTTPProbe `yaml:"http"`
+
+	// open a tcp connection
+	TCP *TCPProbe `yaml:"tcp"`
+
+	// call the grpc health checking protocol
+	GRPC *GRPCProbe `yaml:"grpc"`
+
+	// run a command in the container, succeeds when it exits with 0
+	Exec *ExecProbe `yaml:"exec"`
+
+	// how long to wait after the container started, e.g., 10s
+	InitialDelay string `yaml:"initial_delay"`
+
+	// how often to run the check, e.g., 10s
+	Period string `yaml:"period"`
+
+	// how long to wait for the check to respond, e.g., 1s
+	Timeout string `yaml:"timeout"`
+
+	// the amount of failed checks in a row to be considered unhealthy
+	FailureThreshold int `yaml:"failure_threshold"`
+
+	// the amount of successful checks in a row to be considered healthy
+	SuccessThreshold int `yaml:"success_threshold"`
+}
+
+// define an http get check
+type HTTPProbe struct {
+	// the request path, e.g., /status
+	Path string `yaml:"path"`
+
+	// the container port number or name, defaults to http
+	Port string `yaml:"port"`
+}
+
+// define a tcp connection check
+type TCPProbe struct {
+	// the container port number or name, defaults to http
+	Port string `yaml:"port"`
+}
+
+// define a grpc health check
+type GRPCProbe struct {
+	// the container port number
+	Port int `yaml:"port"`
+
+	// the grpc service name to check, empty checks the whole server
+	Service string `yaml:"service"`
+}
+
+// define a command check
+type ExecProbe struct {
+	// the command to run, e.g., ["cat", "/tmp/healthy"]
+	Command []string `yaml:"command"`
+}
+
+// return the probes with the liveness and readiness checks
+// defaulting to an http check of the given health check url
+func (p *Probes) WithDefaults(healthCheckURL string) *Probes {
+	probes := &Probes{}
+	if p != nil {
+		*probes = *p
+	}
+
+	if probes.Liveness == nil {
+		probes.Liveness = &Probe{HTTP: &HTTPProbe{Path: healthCheckURL}}
+	}
+	if probes.Readiness == nil {
+		probes.Readiness = &Probe{HTTP: &HTTPProbe{Path: healthCheckURL}}
+	}
+
+	return probes
+}
+
+// validate each of the defined probes
+func (p *Probes) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	names := []string{"liveness", "readiness", "startup"}
+	for i, probe := range []*Probe{p.Liveness, p.Readiness, p.Startup} {
+		if probe == nil {
+			continue
+		}
+		if err := probe.Validate(); err != nil {
+			return fmt.Errorf("the %s probe: %w", names[i], err)
+		}
+	}
+
+	// kubernetes only allows a single success for these checks
+	if p.Liveness != nil && p.Liveness.SuccessThreshold > 1 {
+		return fmt.Errorf("the liveness probe: success_threshold must be 1")
+	}
+	if p.Startup != nil && p.Startup.SuccessThreshold > 1 {
+		return fmt.Errorf("the startup probe: success_threshold must be 1")
+	}
+
+	return nil
+}
+
+// validate the named ports the http and tcp checks target are declared
+// by the service. a check without a port targets the http port
+func (p *Probes) ValidatePorts(ports []*Port) error {
+	if p == nil {
+		return nil
+	}
+
+	declared := make(map[string]bool)
+	for _, port := range ports {
+		declared[port.Name] = true
+	}
+
+	names := []string{"liveness", "readiness", "startup"}
+	for i, probe := range []*Probe{p.Liveness, p.Readiness, p.Startup} {
+		if probe == nil {
+			continue
+		}
+
+		var port interface{}
+		switch {
+		case probe.HTTP != nil:
+			port = probe.HTTP.GetPort()
+		case probe.TCP != nil:
+			port = probe.TCP.GetPort()
+		default:
+			continue
+		}
+
+		name, ok := port.(string)
+		if !ok || declared[name] {
+			continue
+		}
+		if name == defaultProbePort {
+			return fmt.Errorf("the %s probe targets the %s port by default but the service has no port named %s - set the probe port explicitly", names[i], defaultProbePort, defaultProbePort)
+		}
+		return fmt.Errorf("the %s probe targets the port %s which is not declared by the service", names[i], name)
+	}
+
+	return nil
+}
+
+// validate the probe defines a single check and valid timings
+func (p *Probe) Validate() error {
+	checks := 0
+	for _, defined := range []bool{p.HTTP != nil, p.TCP != nil, p.GRPC != nil, p.Exec != nil} {
+		if defined {
+			checks++
+		}
+	}
+	if checks != 1 {
+		return fmt.Errorf("expected exactly one of http, tcp, grpc or exec")
+	}
+
+	if p.HTTP != nil && p.HTTP.Path == "" {
+		return fmt.Errorf("the http check requires a path")
+	}
+	if p.GRPC != nil && p.GRPC.Port <= 0 {
+		return fmt.Errorf("the grpc check requires a port")
+	}
+	if p.Exec != nil && len(p.Exec.Command) == 0 {
+		return fmt.Errorf("the exec check requires a command")
+	}
+
+	fields := []string{"initial_delay", "period", "timeout"}
+	for i, value := range []string{p.InitialDelay, p.Period, p.Timeout} {
+		if _, err := parseProbeSeconds(value); err != nil {
+			return fmt.Errorf("invalid %s %s - expected a duration, e.g., 10s", fields[i], value)
+		}
+	}
+
+	if p.FailureThreshold < 0 || p.SuccessThreshold < 0 {
+		return fmt.Errorf("the thresholds must be positive numbers")
+	}
+
+	return nil
+}
+
+// return the initial delay in seconds, 0 if not set
+func (p *Probe) GetInitialDelaySeconds() int {
+	seconds, _ := parseProbeSeconds(p.InitialDelay)
+	return seconds
+}
+
+// return the period in seconds, 0 if not set
+func (p *Probe) GetPeriodSeconds() int {
+	seconds, _ := parseProbeSeconds(p.Period)
+	return seconds
+}
+
+// return the timeout in seconds, 0 if not set
+func (p *Probe) GetTimeoutSeconds() int {
+	seconds, _ := parseProbeSeconds(p.Timeout)
+	return seconds
+}
+
+// return the port number or name to check
+func (h *HTTPProbe) GetPort() interface{} {
+	return parseProbePort(h.Port)
+}
+
+// return the port number or name to check
+func (t *TCPProbe) GetPort() interface{} {
+	return parseProbePort(t.Port)
+}
+
+// return the port as a number if possible, kubernetes
+// treats a quoted number as a port name
+func parseProbePort(port string) interface{} {
+	if port == "" {
+		return defaultProbePort
+	}
+
+	if number, err := strconv.Atoi(port); err == nil {
+		return number
+	}
+
+	return port
+}
+
+// parse the given duration into whole seconds, rounded up
+func parseProbeSeconds(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %s", value)
+	}
+
+	return int((duration + time.Second - 1) / time.Second), nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import "testing"
+
+// TestProbesWithDefaults keeps the health check url for the checks that are not customized
+func TestProbesWithDefaults(t *testing.T) {
+	custom := &Probe{TCP: &TCPProbe{Port: "8080"}}
+	probes := (&Probes{Liveness: custom}).WithDefaults("/status")
+
+	if probes.Liveness != custom {
+		t.Errorf("expected the custom liveness probe to be kept")
+	}
+	if probes.Readiness.HTTP == nil || probes.Readiness.HTTP.Path != "/status" {
+		t.Errorf("expected the readiness probe to default to the health check url")
+	}
+	if probes.Startup != nil {
+		t.Errorf("expected no default startup probe")
+	}
+	if probes.Liveness.TCP.GetPort() != 8080 || probes.Readiness.HTTP.GetPort() != "http" {
+		t.Errorf("expected numeric ports as numbers and the http port by default")
+	}
+}
+
+// TestProbesValidate requires a single valid check per probe
+func TestProbesValidate(t *testing.T) {
+	valid := &Probes{
+		Liveness:  &Probe{HTTP: &HTTPProbe{Path: "/live"}, Period: "10s"},
+		Readiness: &Probe{GRPC: &GRPCProbe{Port: 9000}, SuccessThreshold: 2},
+		Startup:   &Probe{Exec: &ExecProbe{Command: []string{"true"}}, InitialDelay: "1500ms"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid probes, got %v", err)
+	}
+	if valid.Startup.GetInitialDelaySeconds() != 2 {
+		t.Errorf("expected the delay to be rounded up to 2 seconds, got %d", valid.Startup.GetInitialDelaySeconds())
+	}
+
+	cases := map[string]*Probes{
+		"no check":            {Liveness: &Probe{}},
+		"two checks":          {Liveness: &Probe{HTTP: &HTTPProbe{Path: "/"}, TCP: &TCPProbe{}}},
+		"http without path":   {Readiness: &Probe{HTTP: &HTTPProbe{}}},
+		"grpc without port":   {Readiness: &Probe{GRPC: &GRPCProbe{}}},
+		"exec without cmd":    {Startup: &Probe{Exec: &ExecProbe{}}},
+		"invalid period":      {Startup: &Probe{TCP: &TCPProbe{}, Period: "often"}},
+		"liveness successes":  {Liveness: &Probe{TCP: &TCPProbe{}, SuccessThreshold: 3}},
+		"negative thresholds": {Readiness: &Probe{TCP: &TCPProbe{}, FailureThreshold: -1}},
+	}
+	for name, probes := range cases {
+		if err := probes.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestProbesValidatePorts requires the named probe ports to be declared
+func TestProbesValidatePorts(t *testing.T) {
+	ports := []*Port{{Name: "http", Port: 8080}, {Name: "metrics", Port: 9090}}
+	valid := &Probes{
+		Liveness:  &Probe{HTTP: &HTTPProbe{Path: "/live"}},
+		Readiness: &Probe{TCP: &TCPProbe{Port: "metrics"}},
+		Startup:   &Probe{TCP: &TCPProbe{Port: "7000"}},
+	}
+	if err := valid.ValidatePorts(ports); err != nil {
+		t.Errorf("expected valid probe ports, got %v", err)
+	}
+
+	cases := map[string]struct {
+		probes *Probes
+		ports  []*Port
+	}{
+		"unknown port":         {&Probes{Readiness: &Probe{TCP: &TCPProbe{Port: "admin"}}}, ports},
+		"default without http": {(&Probes{}).WithDefaults("/status"), []*Port{{Name: "grpc", Port: 9000}}},
+		"worker without ports": {&Probes{Liveness: &Probe{HTTP: &HTTPProbe{Path: "/live"}}}, nil},
+	}
+	for name, c := range cases {
+		if err := c.probes.ValidatePorts(c.ports); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
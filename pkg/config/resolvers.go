@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/salfatigroup/nopeus/logger"
+)
+
+// define a resolver of the env values referencing an external secret store
+type SecretResolver interface {
+	// resolve the reference without its scheme, e.g., kv/data/api#db_password
+	Resolve(reference string) (string, error)
+}
+
+// the secret resolvers by their scheme, e.g., vault
+var (
+	secretResolvers      = map[string]SecretResolver{}
+	secretResolversMutex sync.RWMutex
+)
+
+// register the default secret resolvers
+func init() {
+	RegisterSecretResolver("file", &FileResolver{})
+	RegisterSecretResolver("vault", &VaultResolver{})
+}
+
+// register the resolver of the env values with the given scheme,
+// e.g., vault for vault://kv/data/api#db_password
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolversMutex.Lock()
+	defer secretResolversMutex.Unlock()
+
+	secretResolvers[scheme] = resolver
+}
+
+// return the resolver of the scheme if any
+func getSecretResolver(scheme string) SecretResolver {
+	secretResolversMutex.RLock()
+	defer secretResolversMutex.RUnlock()
+
+	return secretResolvers[scheme]
+}
+
+// resolve the value if it references a registered secret store.
+// returns false for plain values, e.g., https://example.com
+func resolveSecretReference(value string) (string, bool, error) {
+	scheme, reference, ok := strings.Cut(value, "://")
+	if !ok {
+		return "", false, nil
+	}
+
+	resolver := getSecretResolver(scheme)
+	if resolver == nil {
+		return "", false, nil
+	}
+
+	resolved, err := resolver.Resolve(reference)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to resolve %s: %w", value, err)
+	}
+
+	// values sourced from a secret store are masked in the logs
+	logger.RegisterSensitive(resolved)
+	return resolved, true, nil
+}
+
+// resolve the env values from local files, e.g., file://secrets/api-key
+type FileResolver struct {
+	// the directory relative paths are resolved from
+	// defaults to the working directory
+	Dir string
+}
+
+// return the content of the file without the trailing newlines
+func (r *FileResolver) Resolve(reference string) (string, error) {
+	file := reference
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(r.Dir, file)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
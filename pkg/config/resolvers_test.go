@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/salfatigroup/nopeus/logger"
+)
+
+// start a vault stand-in serving a kv version 2 and a kv version 1 secret
+// to the approle token, returns the server and the number of secret reads
+func newVaultServer(t *testing.T) (*httptest.Server, *int) {
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v1/auth/approle/login":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["role_id"] != "role" || body["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errors": ["invalid role or secret id"]}`))
+				return
+			}
+			w.Write([]byte(`{"auth": {"client_token": "approle-token"}}`))
+
+		case r.Header.Get("X-Vault-Token") != "approle-token":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+
+		case r.URL.Path == "/v1/kv/data/api":
+			reads++
+			w.Write([]byte(`{"data": {"data": {"db_password": "s3cr3t", "port": 5432}, "metadata": {"version": 3}}}`))
+
+		case r.URL.Path == "/v1/secret/api":
+			reads++
+			w.Write([]byte(`{"data": {"api_key": "k3y"}}`))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &reads
+}
+
+// TestVaultResolverAppRole reads the kv secrets after an approle login
+func TestVaultResolverAppRole(t *testing.T) {
+	server, reads := newVaultServer(t)
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("HOME", t.TempDir())
+	resolver := &VaultResolver{Address: server.URL, RoleID: "role", SecretID: "secret"}
+
+	tests := map[string]string{
+		"kv/data/api#db_password": "s3cr3t",
+		"kv/data/api#port":        "5432",
+		"secret/api#api_key":      "k3y",
+	}
+	for reference, expected := range tests {
+		value, err := resolver.Resolve(reference)
+		if err != nil {
+			t.Fatalf("failed to resolve %s: %v", reference, err)
+		}
+		if value != expected {
+			t.Errorf("expected %s for %s, got %s", expected, reference, value)
+		}
+	}
+	if *reads != 2 {
+		t.Errorf("expected each path to be read once, got %d reads", *reads)
+	}
+
+	for _, reference := range []string{"kv/data/api#missing", "kv/data/missing#key", "kv/data/api"} {
+		if _, err := resolver.Resolve(reference); err == nil {
+			t.Errorf("expected an error for %s", reference)
+		}
+	}
+}
+
+// TestVaultResolverToken uses the token of the environment
+func TestVaultResolverToken(t *testing.T) {
+	server, _ := newVaultServer(t)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "approle-token")
+
+	value, err := (&VaultResolver{}).Resolve("secret/api#api_key")
+	if err != nil || value != "k3y" {
+		t.Errorf("expected the secret with the token of the environment, got %q, %v", value, err)
+	}
+
+	t.Setenv("VAULT_TOKEN", "invalid")
+	if _, err := (&VaultResolver{}).Resolve("secret/api#api_key"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+}
+
+// TestParseEnvironmentReferences resolves the references and masks their values
+func TestParseEnvironmentReferences(t *testing.T) {
+	server, _ := newVaultServer(t)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "approle-token")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("t0k3n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	RegisterSecretResolver("file", &FileResolver{Dir: dir})
+	t.Cleanup(func() { RegisterSecretResolver("file", &FileResolver{}) })
+
+	service := &Service{
+		EnvironmentVariables: EnvironmentVariables{
+			"TOKEN":   "file://token",
+			"API_URL": "https://example.com",
+		},
+		Secrets: map[string]string{"DB_PASSWORD": "vault://kv/data/api#db_password"},
+	}
+	if err := service.ParseEnvironmentVariables("production"); err != nil {
+		t.Fatal(err)
+	}
+
+	env := service.GetEnvironmentVariables("production")
+	if env["TOKEN"] != "t0k3n" || env["API_URL"] != "https://example.com" {
+		t.Errorf("expected the file reference to resolve and the url to be kept, got %v", env)
+	}
+	if service.GetSecrets("production")["DB_PASSWORD"] != "s3cr3t" {
+		t.Errorf("expected the vault reference to resolve, got %v", service.GetSecrets("production"))
+	}
+	if redacted := logger.Redact("token t0k3n password s3cr3t"); strings.Contains(redacted, "t0k3n") || strings.Contains(redacted, "s3cr3t") {
+		t.Errorf("expected the resolved values to be masked, got %s", redacted)
+	}
+
+	service.EnvironmentVariables["TOKEN"] = "file://missing"
+	if err := service.ParseEnvironmentVariables("production"); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// define the compute resources of the service containers
+type Resources struct {
+	// the resources reserved for each container
+	Requests *ResourceList `yaml:"requests"`
+
+	// the maximum resources each container can use
+	Limits *ResourceList `yaml:"limits"`
+}
+
+// define an amount of cpu and memory
+type ResourceList struct {
+	// e.g., 250m or 1
+	CPU string `yaml:"cpu"`
+
+	// e.g., 256Mi or 1Gi
+	Memory string `yaml:"memory"`
+}
+
+// validate the resource quantities and that the limits are not below the requests
+func (r *Resources) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	requests, err := r.Requests.parse("requests")
+	if err != nil {
+		return err
+	}
+	limits, err := r.Limits.parse("limits")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"cpu", "memory"} {
+		limit, hasLimit := limits[name]
+		request, hasRequest := requests[name]
+		if hasLimit && hasRequest && limit.Cmp(request) < 0 {
+			return fmt.Errorf("the %s limit %s is below the %s request %s", name, limit.String(), name, request.String())
+		}
+	}
+
+	return nil
+}
+
+// return true if the given resource is requested, e.g., cpu
+func (r *Resources) HasRequest(name string) bool {
+	return r != nil && r.Requests.get(name) != ""
+}
+
+// return the quantity of the given resource, empty if not set
+func (l *ResourceList) get(name string) string {
+	if l == nil {
+		return ""
+	}
+
+	switch name {
+	case "cpu":
+		return l.CPU
+	case "memory":
+		return l.Memory
+	default:
+		return ""
+	}
+}
+
+// parse the quantities that are set by their resource name
+func (l *ResourceList) parse(field string) (map[string]resource.Quantity, error) {
+	quantities := make(map[string]resource.Quantity)
+	for _, name := range []string{"cpu", "memory"} {
+		value := l.get(name)
+		if value == "" {
+			continue
+		}
+
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s %s - expected a quantity, e.g., 250m or 256Mi", field, name, value)
+		}
+		quantities[name] = quantity
+	}
+
+	return quantities, nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+// TestResourcesValidate rejects limits below the requests
+func TestResourcesValidate(t *testing.T) {
+	valid := &Resources{
+		Requests: &ResourceList{CPU: "250m", Memory: "256Mi"},
+		Limits:   &ResourceList{CPU: "1", Memory: "1Gi"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid resources, got %v", err)
+	}
+
+	cases := map[string]*Resources{
+		"cpu below request":    {Requests: &ResourceList{CPU: "500m"}, Limits: &ResourceList{CPU: "0.25"}},
+		"memory below request": {Requests: &ResourceList{Memory: "1Gi"}, Limits: &ResourceList{Memory: "512Mi"}},
+		"invalid quantity":     {Requests: &ResourceList{CPU: "lots"}},
+	}
+	for name, resources := range cases {
+		if err := resources.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestAutoscalingValidate checks the replicas range and the targets requests
+func TestAutoscalingValidate(t *testing.T) {
+	resources := &Resources{Requests: &ResourceList{CPU: "250m"}}
+
+	valid := &Autoscaling{MinReplicas: 2, MaxReplicas: 10, Metric: &AutoscalingMetric{Name: "http_requests_per_second", Target: "100"}}
+	if err := valid.Validate(resources); err != nil {
+		t.Errorf("expected valid autoscaling, got %v", err)
+	}
+
+	cases := map[string]*Autoscaling{
+		"min above max":          {MinReplicas: 5, MaxReplicas: 2},
+		"missing max":            {MinReplicas: 1},
+		"memory without request": {MaxReplicas: 3, TargetMemory: 70},
+		"metric without name":    {MaxReplicas: 3, Metric: &AutoscalingMetric{Target: "100"}},
+		"metric without target":  {MaxReplicas: 3, Metric: &AutoscalingMetric{Name: "queue_length"}},
+	}
+	for name, autoscaling := range cases {
+		if err := autoscaling.Validate(resources); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// the default cpu target relies on the cpu request
+	if err := (&Autoscaling{MaxReplicas: 3}).Validate(nil); err == nil {
+		t.Errorf("expected the default cpu target to require a cpu request")
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"os/exec"
 	"path/filepath"
 
-	"github.com/salfatigroup/nopeus/cli/util"
 	"github.com/salfatigroup/nopeus/helm"
 	helmrepo "helm.sh/helm/v3/pkg/repo"
 )
@@ -36,6 +35,7 @@ type RuntimeConfig struct {
 	RemoveKeepExecutionFiles bool
 
 	// a path to terraform binary
+	// use GetTerraformExecutablePath to resolve it
 	TerraformExecutablePath string
 
 	// dry run mode - will not apply any changes to the cloud
@@ -53,6 +53,12 @@ type RuntimeConfig struct {
 
 	// nopeus cloud token
 	NopeusCloudToken string
+
+	// print the raw terraform and helm logs
+	Verbose bool
+
+	// print only the steps summary, suitable for CI
+	Quiet bool
 }
 
 // create a new instance of the runtime config with all the required default values
@@ -60,15 +66,6 @@ func NewRuntimeConfig() *RuntimeConfig {
 	// get the ~/.nopeus directory
 	currentDir, _ := os.Getwd()
 	rootNopeusDir := filepath.Join(currentDir, ".nopeus")
-	terraformPath, err := exec.LookPath("terraform")
-	if err != nil {
-		fmt.Println(
-			"💥 ",
-			util.GradientText("[NOPEUS::TERMINATE]", "#db2777", "#f9a8d4"),
-			" - terraform not found in PATH",
-		)
-		os.Exit(1)
-	}
 
 	// return configs
 	runtime := &RuntimeConfig{
@@ -89,7 +86,8 @@ func NewRuntimeConfig() *RuntimeConfig {
 		RemoveKeepExecutionFiles: false,
 
 		// a path to terraform binary
-		TerraformExecutablePath: terraformPath,
+		// resolved lazily once terraform is required
+		TerraformExecutablePath: "",
 
 		// by default ignore the dry run mode
 		DryRun: false,
@@ -158,6 +156,12 @@ func (c *NopeusConfig) Init() error {
 	return nil
 }
 
+// parse the nopeus config without initializing the runtime,
+// for commands that only read the user configs
+func (c *NopeusConfig) Load() error {
+	return c.parseConfig()
+}
+
 // define the nopeus config
 func (c *NopeusConfig) SetConfigPath(path string) {
 	c.Runtime.ConfigPath = path
@@ -177,3 +181,50 @@ func GetDefaultConfigPath() string {
 func (c *NopeusConfig) SetDryRun(dryRun bool) {
 	c.Runtime.DryRun = dryRun
 }
+
+// return the path to the terraform binary. the binary is resolved only
+// when terraform is required - either the pinned terraform version installed
+// into the nopeus bin directory or the terraform found in PATH
+func (c *NopeusConfig) GetTerraformExecutablePath() (string, error) {
+	if c.Runtime.TerraformExecutablePath != "" {
+		return c.Runtime.TerraformExecutablePath, nil
+	}
+
+	tfConfig := c.CAL.GetTerraform()
+	if version := tfConfig.GetVersion(); version != "" {
+		// resolve the mirror relative to the nopeus config
+		mirror := tfConfig.Mirror
+		if mirror != "" && !filepath.IsAbs(mirror) {
+			mirror = filepath.Join(filepath.Dir(c.Runtime.ConfigPath), mirror)
+		}
+
+		signingKey := tfConfig.SigningKey
+		if signingKey != "" && !filepath.IsAbs(signingKey) {
+			signingKey = filepath.Join(filepath.Dir(c.Runtime.ConfigPath), signingKey)
+		}
+
+		installer := &terraformInstaller{
+			version:    version,
+			mirror:     mirror,
+			checksum:   tfConfig.Checksum,
+			signingKey: signingKey,
+			binDir:     filepath.Join(c.Runtime.RootNopeusDir, "bin"),
+		}
+
+		execPath, err := installer.install()
+		if err != nil {
+			return "", fmt.Errorf("failed to install terraform %s: %w", version, err)
+		}
+
+		c.Runtime.TerraformExecutablePath = execPath
+		return execPath, nil
+	}
+
+	execPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return "", fmt.Errorf("terraform not found in PATH - install terraform or pin terraform.version in the nopeus config")
+	}
+
+	c.Runtime.TerraformExecutablePath = execPath
+	return execPath, nil
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/salfatigroup/nopeus/kubernetes"
+	"github.com/salfatigroup/nopeus/logger"
+	yaml "gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// define the raw environment variables of a service. each value is
+// either a string or a mapping with the value and a secret marker, e.g.,
+// API_KEY: { value: "${API_KEY}", secret: true }
+type EnvironmentVariables map[string]string
+
+// define an environment variable in its mapping form
+type environmentValue struct {
+	// the raw value of the variable
+	Value string `yaml:"value"`
+
+	// store the variable in a kubernetes secret
+	Secret bool `yaml:"secret"`
+}
+
+// decode the environment variables from both of their forms
+func (e *EnvironmentVariables) UnmarshalYAML(node *yaml.Node) error {
+	values, err := decodeEnvironmentValues(node)
+	if err != nil {
+		return err
+	}
+
+	*e = make(EnvironmentVariables)
+	for key, value := range values {
+		(*e)[key] = value.Value
+	}
+
+	return nil
+}
+
+// decode the service and move the environment
+// variables marked as secret to the service secrets
+func (s *Service) UnmarshalYAML(node *yaml.Node) error {
+	type plain Service
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+
+	var raw struct {
+		Environment yaml.Node `yaml:"environment"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	if raw.Environment.Kind == 0 {
+		return nil
+	}
+
+	values, err := decodeEnvironmentValues(&raw.Environment)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if !value.Secret {
+			continue
+		}
+
+		if s.Secrets == nil {
+			s.Secrets = make(map[string]string)
+		}
+		if _, ok := s.Secrets[key]; ok {
+			return fmt.Errorf("the variable %s is defined in both environment and secrets", key)
+		}
+		s.Secrets[key] = value.Value
+		delete(s.EnvironmentVariables, key)
+	}
+
+	return nil
+}
+
+// decode the environment variables mapping into their values
+func decodeEnvironmentValues(node *yaml.Node) (map[string]*environmentValue, error) {
+	nodes := make(map[string]yaml.Node)
+	if err := node.Decode(&nodes); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]*environmentValue)
+	for key, valueNode := range nodes {
+		value := &environmentValue{}
+		if valueNode.Kind == yaml.MappingNode {
+			if err := valueNode.Decode(value); err != nil {
+				return nil, fmt.Errorf("invalid environment variable %s: %w", key, err)
+			}
+		} else if err := valueNode.Decode(&value.Value); err != nil {
+			return nil, fmt.Errorf("invalid environment variable %s: %w", key, err)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// define the kubernetes secret holding the secret variables of a service
+type ServiceSecrets struct {
+	// the service the secrets belong to
+	Service string
+
+	// the secret name, changes with the secret values
+	Name string
+
+	// the secret values by their variable name
+	Data map[string]string
+}
+
+// return the secret of the given variables, nil if there are none.
+// only a hash of the values is part of the secret name
+func NewServiceSecrets(service string, data map[string]string) *ServiceSecrets {
+	if len(data) == 0 {
+		return nil
+	}
+
+	hash := sha256.New()
+	for _, key := range sortedKeys(data) {
+		fmt.Fprintf(hash, "%s\x00%s\x00", key, data[key])
+	}
+
+	return &ServiceSecrets{
+		Service: service,
+		Name:    fmt.Sprintf("%s-secrets-%s", service, shortHash(hash.Sum(nil))),
+		Data:    data,
+	}
+}
+
+// define a variable read from the key of a kubernetes secret
+// only the reference is rendered into the helm values and the checksum
+type SecretEnvVar struct {
+	// the variable name
+	Name string
+
+	// the secret name
+	Secret string
+
+	// the key of the variable in the secret
+	Key string
+}
+
+// return the variables read from the secret
+func (s *ServiceSecrets) GetEnvVars() []*SecretEnvVar {
+	envVars := []*SecretEnvVar{}
+	for _, key := range sortedKeys(s.Data) {
+		envVars = append(envVars, &SecretEnvVar{Name: key, Secret: s.Name, Key: key})
+	}
+
+	return envVars
+}
+
+// return the labels of the secrets of the service
+func (s *ServiceSecrets) labels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "nopeus",
+		"nopeus.salfati.group/service": s.Service,
+		"nopeus.salfati.group/secrets": "true",
+	}
+}
+
+// create the secret in the cluster
+func (s *ServiceSecrets) Apply(kubeContext string, namespace string) error {
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err != nil {
+		return err
+	}
+
+	return kubernetes.ApplySecret(context.Background(), client, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: namespace, Labels: s.labels()},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: s.Data,
+	})
+}
+
+// remove the secrets of the previous values
+// a failure is only logged since it doesn't affect the service
+func (s *ServiceSecrets) Prune(kubeContext string, namespace string) {
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err == nil {
+		err = kubernetes.PruneSecrets(context.Background(), client, namespace, s.labels(), s.Name)
+	}
+	if err != nil {
+		logger.Debugf("failed to remove the previous secrets of service %s: %v", s.Service, err)
+	}
+}
+
+// return the keys of the map sorted
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// TestServiceSecretMarker moves the variables marked as secret to the secrets
+func TestServiceSecretMarker(t *testing.T) {
+	content := `
+environment:
+  LOG_LEVEL: debug
+  API_KEY:
+    value: "${API_KEY}"
+    secret: true
+  REGION:
+    value: us-east-1
+secrets:
+  DB_PASSWORD: "${DB_PASSWORD}"
+`
+	service := &Service{}
+	if err := yaml.Unmarshal([]byte(content), service); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(service.EnvironmentVariables) != 2 || service.EnvironmentVariables["LOG_LEVEL"] != "debug" || service.EnvironmentVariables["REGION"] != "us-east-1" {
+		t.Errorf("expected the plain variables in the environment, got %v", service.EnvironmentVariables)
+	}
+	if len(service.Secrets) != 2 || service.Secrets["API_KEY"] != "${API_KEY}" || service.Secrets["DB_PASSWORD"] != "${DB_PASSWORD}" {
+		t.Errorf("expected the marked variables in the secrets, got %v", service.Secrets)
+	}
+}
+
+// TestServiceSecretMarkerConflict rejects a variable defined twice
+func TestServiceSecretMarkerConflict(t *testing.T) {
+	content := `
+environment:
+  API_KEY:
+    value: "${API_KEY}"
+    secret: true
+secrets:
+  API_KEY: "${OTHER_API_KEY}"
+`
+	if err := yaml.Unmarshal([]byte(content), &Service{}); err == nil {
+		t.Errorf("expected an error for a variable defined in both environment and secrets")
+	}
+}
+
+// TestParseSecrets resolves the secrets from the environment
+func TestParseSecrets(t *testing.T) {
+	t.Setenv("TEST_SECRETS_API_KEY", "s3cr3t")
+	service := &Service{
+		EnvironmentVariables: EnvironmentVariables{"LOG_LEVEL": "debug"},
+		Secrets:              map[string]string{"API_KEY": "${TEST_SECRETS_API_KEY}"},
+	}
+	if err := service.ParseEnvironmentVariables("production"); err != nil {
+		t.Fatal(err)
+	}
+
+	if service.GetSecrets("production")["API_KEY"] != "s3cr3t" {
+		t.Errorf("expected the resolved secret, got %v", service.GetSecrets("production"))
+	}
+	if _, ok := service.GetEnvironmentVariables("production")["API_KEY"]; ok {
+		t.Errorf("expected the secret to be left out of the environment")
+	}
+
+	service.EnvironmentVariables["API_KEY"] = "plain"
+	if err := service.ParseEnvironmentVariables("production"); err == nil {
+		t.Errorf("expected an error for a variable defined in both environment and secrets")
+	}
+}
+
+// TestNewServiceSecrets names the secret after its values
+func TestNewServiceSecrets(t *testing.T) {
+	if NewServiceSecrets("api", nil) != nil {
+		t.Errorf("expected no secret without secret variables")
+	}
+
+	secrets := NewServiceSecrets("api", map[string]string{"API_KEY": "a", "DB_PASSWORD": "b"})
+	again := NewServiceSecrets("api", map[string]string{"DB_PASSWORD": "b", "API_KEY": "a"})
+	if secrets.Name != again.Name {
+		t.Errorf("expected a stable secret name, got %s and %s", secrets.Name, again.Name)
+	}
+
+	changed := NewServiceSecrets("api", map[string]string{"API_KEY": "c", "DB_PASSWORD": "b"})
+	if changed.Name == secrets.Name {
+		t.Errorf("expected the secret name to change with the values")
+	}
+
+	envVars := secrets.GetEnvVars()
+	if len(envVars) != 2 || envVars[0].Name != "API_KEY" || envVars[1].Name != "DB_PASSWORD" {
+		t.Fatalf("expected the sorted variables of the secret, got %v", envVars)
+	}
+	if envVars[1].Secret != secrets.Name || envVars[1].Key != "DB_PASSWORD" {
+		t.Errorf("expected the variable to reference its key in the secret, got %+v", *envVars[1])
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	helmclient "github.com/mittwald/go-helm-client"
 )
@@ -26,6 +29,39 @@ type HelmRendererValues struct {
 	Custom map[string]interface{} `yaml:"-"`
 }
 
+// return the environment variables rendered as is
+func (v *HelmRendererValues) GetPlainEnvironment() map[string]string {
+	plain := make(map[string]string)
+	for key, value := range v.Environment {
+		if !isExpandedVariable(value) {
+			plain[key] = value
+		}
+	}
+
+	return plain
+}
+
+// return the environment variables expanding other variables, e.g., a url
+// reading $(DB_PASSWORD). they are listed after the variables they expand
+func (v *HelmRendererValues) GetExpandedEnvironment() map[string]string {
+	expanded := make(map[string]string)
+	for key, value := range v.Environment {
+		if isExpandedVariable(value) {
+			expanded[key] = value
+		}
+	}
+
+	return expanded
+}
+
+// return true if kubernetes expands a variable in the value
+func isExpandedVariable(value string) bool {
+	return strings.Contains(value, "$(")
+}
+
+// the time to wait for a service to become ready after its helm chart is applied
+const DefaultRolloutTimeout = 5 * time.Minute
+
 // the interface that will be used by each
 // service type to implement their own rendering and parsing
 type ServiceTemplateData interface {
@@ -56,23 +92,162 @@ type ServiceTemplateData interface {
 
 	// returns the service checksum
 	GetChecksum() (string, error)
+
+	// return the names of the services that must be ready before this one
+	GetDependencies() []string
 }
 
-// return the default nopeus microservice template data
+// define the supported service kinds
+const (
+	// a long running service that receives traffic
+	ServiceKindService = "service"
+
+	// a long running background process without a
+	// kubernetes service, ingress or health check
+	ServiceKindWorker = "worker"
+
+	// a process that runs on a cron schedule
+	ServiceKindCronJob = "cronjob"
+)
+
+// the chart of every service kind, the kind picks the workload it renders
+const defaultMicroserviceChart = "salfatigroup/default-microservice"
+
+// return the template data of the service based on its kind
 func NewServiceTemplateData(cfg *NopeusConfig, name string, service *Service, env string, envData *EnvironmentConfig) (ServiceTemplateData, error) {
+	switch service.GetKind() {
+	case ServiceKindService:
+		return NewMicroserviceTemplateData(cfg, name, service, env)
+	case ServiceKindWorker:
+		return NewWorkerTemplateData(cfg, name, service, env)
+	case ServiceKindCronJob:
+		return NewCronJobTemplateData(cfg, name, service, env)
+	default:
+		return &NopeusDefaultMicroservice{}, fmt.Errorf("service %s: unsupported kind %s - expected %s, %s or %s", name, service.Kind, ServiceKindService, ServiceKindWorker, ServiceKindCronJob)
+	}
+}
+
+// return the default nopeus microservice template data
+func NewMicroserviceTemplateData(cfg *NopeusConfig, name string, service *Service, env string) (ServiceTemplateData, error) {
+	if service.Schedule != "" {
+		return &NopeusDefaultMicroservice{}, fmt.Errorf("service %s: a %s can't have a schedule - use the %s kind instead", name, ServiceKindService, ServiceKindCronJob)
+	}
+
+	microservice, err := newMicroservice(cfg, name, service, env)
+	if err != nil {
+		return &NopeusDefaultMicroservice{}, err
+	}
+
+	// progressive rollouts shift the traffic through the api gateway
+	if microservice.strategy.IsProgressive() && service.Ingress == nil {
+		return &NopeusDefaultMicroservice{}, fmt.Errorf("service %s: the %s strategy requires an ingress", name, microservice.strategy.GetType())
+	}
+
+	// the candidate release can't share the disks of the stateful set replicas
+	if microservice.strategy.IsProgressive() && microservice.workload.kind == workloadKindStatefulSet {
+		return &NopeusDefaultMicroservice{}, fmt.Errorf("service %s: the %s strategy can't be used with %s volumes", name, microservice.strategy.GetType(), AccessModeReadWriteOnce)
+	}
+
+	if err := setReplicas(microservice, service); err != nil {
+		return &NopeusDefaultMicroservice{}, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	microservice.ingress = service.Ingress
+
+	// the chart only defaults to an http port when no ports are declared
+	// so the probes must target one of the declared ports
+	if len(service.Ports) > 0 {
+		if err := service.GetProbes().WithDefaults(service.GetHealthCheckURL()).ValidatePorts(service.Ports); err != nil {
+			return &NopeusDefaultMicroservice{}, fmt.Errorf("service %s: %w", name, err)
+		}
+	}
+
+	// the health check url is rendered as is unless the probes are customized
+	if service.GetProbes() == nil {
+		microservice.Values.Custom["HealthCheckURL"] = service.GetHealthCheckURL()
+	} else {
+		microservice.Values.Custom["Probes"] = service.GetProbes().WithDefaults(service.GetHealthCheckURL())
+	}
+
+	return microservice, nil
+}
+
+// create the microservice template data shared by all the service
+// kinds and validate the configs that apply to each of them
+func newMicroservice(cfg *NopeusConfig, name string, service *Service, env string) (*NopeusDefaultMicroservice, error) {
 	cloudVendor, err := cfg.CAL.GetCloudVendor()
 	if err != nil {
-		return &NopeusDefaultMicroservice{}, nil
+		return nil, err
 	}
 	workingDir := filepath.Join(cfg.Runtime.TmpFileLocation, cloudVendor, env)
 
-	return &NopeusDefaultMicroservice{
+	rolloutTimeout, err := service.GetRolloutTimeout()
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	strategy := service.GetStrategy()
+	if err := strategy.Validate(); err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	if err := service.GetHooks().Validate(); err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	if err := service.GetResources().Validate(); err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	if err := service.GetProbes().Validate(); err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	if err := ValidatePorts(service.Ports); err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	// the files are mounted as volumes of their configmap
+	files, fileVolumes, err := LoadServiceFiles(filepath.Dir(cfg.Runtime.ConfigPath), name, service.Files)
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+	volumes := append(append([]*Volume{}, service.Volumes...), fileVolumes...)
+	if err := ValidateVolumes(volumes); err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	// the discovery variables don't override the variables of the service
+	discovery, err := cfg.CAL.GetDiscoveryVariables(name, env, cfg.Runtime.DefaultNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+	for key, value := range discovery.Environment {
+		if !service.DefinesVariable(key) {
+			service.AddEnvironmentVariable(key, value, env)
+		}
+	}
+	secretEnv := []*SecretEnvVar{}
+	for _, envVar := range discovery.Secrets {
+		if !service.DefinesVariable(envVar.Name) {
+			secretEnv = append(secretEnv, envVar)
+		}
+	}
+
+	microservice := &NopeusDefaultMicroservice{
 		Name:           name,
-		HelmPackage:    "salfatigroup/default-microservice",
+		HelmPackage:    defaultMicroserviceChart,
 		ValuesTemplate: "service.values.yaml",
 		ValuesPath:     fmt.Sprintf("%s/%s.values.yaml", workingDir, name),
 		Namespace:      cfg.Runtime.DefaultNamespace,
 		DryRun:         cfg.Runtime.DryRun,
+		rolloutTimeout: rolloutTimeout,
+		atomic:         service.IsAtomic(cfg.CAL.IsAtomic()),
+		strategy:       strategy,
+		hooks:          service.GetHooks(),
+		files:          files,
+		secrets:        NewServiceSecrets(name, service.GetSecrets(env)),
+		dependsOn:      service.GetDependencies(),
 		Values: &HelmRendererValues{
 			Name:        name,
 			Image:       service.GetImage(),
@@ -80,11 +255,55 @@ func NewServiceTemplateData(cfg *NopeusConfig, name string, service *Service, en
 			Environment: service.GetEnvironmentVariables(env),
 			Custom: map[string]interface{}{
 				"ImagePullSecret": "dockerconfig",
-				"Replicas":        service.GetReplicas(),
-				"HealthCheckURL":  service.GetHealthCheckURL(),
 			},
 		},
-	}, nil
+	}
+
+	if service.GetResources() != nil {
+		microservice.Values.Custom["Resources"] = service.GetResources()
+	}
+	if len(service.Ports) > 0 {
+		microservice.Values.Custom["Ports"] = service.Ports
+	}
+	if microservice.secrets != nil {
+		secretEnv = append(secretEnv, microservice.secrets.GetEnvVars()...)
+	}
+	if len(secretEnv) > 0 {
+		sort.Slice(secretEnv, func(i, j int) bool { return secretEnv[i].Name < secretEnv[j].Name })
+		microservice.Values.Custom["SecretEnvironment"] = secretEnv
+	}
+
+	// long running services get a disk per replica through a stateful set
+	if len(volumes) > 0 {
+		volumes = resolveVolumes(volumes, cloudVendor, service.GetKind() != ServiceKindCronJob)
+		microservice.Values.Custom["Volumes"] = volumes
+		microservice.Values.Custom["StatefulSet"] = hasClaimTemplates(volumes)
+	}
+	if service.GetKind() != ServiceKindCronJob {
+		microservice.workload = newWorkloadSpec(volumes)
+	}
+
+	return microservice, nil
+}
+
+// set the fixed amount of replicas or the autoscaling configs
+// of a long running service
+func setReplicas(microservice *NopeusDefaultMicroservice, service *Service) error {
+	autoscaling := service.GetAutoscaling()
+	if autoscaling == nil {
+		microservice.Values.Custom["Replicas"] = service.GetReplicas()
+		return nil
+	}
+
+	if service.GetReplicas() != 0 {
+		return fmt.Errorf("replicas can't be set with autoscaling - use autoscaling.min_replicas instead")
+	}
+	if err := autoscaling.Validate(service.GetResources()); err != nil {
+		return err
+	}
+
+	microservice.Values.Custom["Autoscaling"] = autoscaling
+	return nil
 }
 
 // return the database service tempalte data
@@ -99,6 +318,10 @@ func NewDatabaseServiceTemplateData(cfg *NopeusConfig, db *DatabaseStorage, env
 	if err != nil {
 		return &NopeusStorageMicroservice{}, err
 	}
+	connection, ok := databaseConnections[db.Type]
+	if !ok {
+		return &NopeusStorageMicroservice{}, fmt.Errorf("unsupported database type: %s", db.Type)
+	}
 
 	return &NopeusStorageMicroservice{
 		Name:           db.Name,
@@ -107,10 +330,13 @@ func NewDatabaseServiceTemplateData(cfg *NopeusConfig, db *DatabaseStorage, env
 		ValuesPath:     fmt.Sprintf("%s/%s.values.yaml", workingDir, db.Name),
 		Namespace:      cfg.Runtime.DefaultNamespace,
 		dryRun:         cfg.Runtime.DryRun,
+		dependsOn:      db.DependsOn,
 		Values: &HelmRendererValues{
 			Name:    db.Name,
 			Image:   dbImage,
 			Version: db.Version,
+			// the database the discovery variables connect to
+			Custom: map[string]interface{}{"Database": connection.database},
 		},
 	}, nil
 }
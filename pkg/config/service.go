@@ -2,8 +2,7 @@ package config
 
 import (
 	"fmt"
-	"os"
-	"strings"
+	"time"
 
 	"github.com/salfatigroup/nopeus/logger"
 )
@@ -12,6 +11,10 @@ import (
 // each service represent a microservice that will be
 // deployed to the final cluster
 type Service struct {
+	// the kind of workload to run - service, worker or cronjob
+	// defaults to service
+	Kind string `yaml:"kind"`
+
 	// the docker image to be used
 	Image string `yaml:"image"`
 
@@ -19,26 +22,97 @@ type Service struct {
 	Version string `yaml:"version"`
 
 	// raw environment variables
-	EnvironmentVariables map[string]string `yaml:"environment"`
+	EnvironmentVariables EnvironmentVariables `yaml:"environment"`
 	// parsed enviroenment variables
 	// envVars [environment name] [environment variable name] environment variable value
 	envVars map[string]map[string]string `yaml:"-"`
 
+	// raw environment variables stored in a kubernetes secret
+	// and left out of the helm values
+	Secrets map[string]string `yaml:"secrets"`
+	// parsed secret variables by environment name
+	secretVars map[string]map[string]string `yaml:"-"`
+
+	// the ports the service listens on, defaults to
+	// a single http port set by the PORT environment variable
+	Ports []*Port `yaml:"ports"`
+
+	// the volumes mounted into the service containers
+	Volumes []*Volume `yaml:"volumes"`
+
+	// the local files mounted into the service containers, by their path
+	// relative to the nopeus.yaml directory, e.g., ./nginx.conf: /etc/nginx/nginx.conf
+	Files map[string]string `yaml:"files"`
+
 	// helath check url
 	HealthCheckURL string `yaml:"health_url"`
 
+	// the liveness, readiness and startup checks of the service
+	// the liveness and readiness checks default to the health check url
+	Probes *Probes `yaml:"probes"`
+
 	// amount of replicas
 	Replicas int `yaml:"replicas"`
 
+	// the cpu and memory requests and limits of the service containers
+	Resources *Resources `yaml:"resources"`
+
+	// scale the replicas with a horizontal pod autoscaler instead
+	// of a fixed amount of replicas
+	Autoscaling *Autoscaling `yaml:"autoscaling"`
+
+	// how long to wait for the service to become ready, e.g., 10m
+	// defaults to 5m
+	RolloutTimeout string `yaml:"rollout_timeout"`
+
+	// roll back to the previous release when the rollout fails
+	// defaults to the global atomic config
+	Atomic *bool `yaml:"atomic"`
+
+	// define how a new version is rolled out - rolling, blue-green or canary
+	Strategy *Strategy `yaml:"strategy"`
+
+	// commands that run as kubernetes jobs around the rollout
+	Hooks *ServiceHooks `yaml:"hooks"`
+
+	// the services and databases that must be ready before this service
+	DependsOn []string `yaml:"depends_on"`
+
+	// the services that are injected with the discovery variables
+	// of this service, e.g., API_URL. defaults to all the services
+	ExposeTo []string `yaml:"expose_to"`
+
 	// custom ingress definitions
 	// if not a single ingress is presented the cluster will
 	// stay private
 	Ingress *Ingress `yaml:"ingress"`
 
+	// the cron schedule of a cronjob, e.g., "*/5 * * * *"
+	Schedule string `yaml:"schedule"`
+
+	// how overlapping runs of a cronjob are handled - allow, forbid or replace
+	// defaults to allow
+	ConcurrencyPolicy string `yaml:"concurrency_policy"`
+
+	// the amount of successful cronjob runs to keep, defaults to 3
+	SuccessfulJobsHistoryLimit *int `yaml:"successful_jobs_history_limit"`
+
+	// the amount of failed cronjob runs to keep, defaults to 1
+	FailedJobsHistoryLimit *int `yaml:"failed_jobs_history_limit"`
+
 	// extend the final k8s configs with whatever you want
 	Extend map[string]interface{} `yaml:"extend"`
 }
 
+// return the kind of the service, defaults to service
+func (s *Service) GetKind() string {
+	if s.Kind == "" {
+		return ServiceKindService
+	}
+
+	return s.Kind
+}
+
 // return the image name
 func (s *Service) GetImage() string {
 	return s.Image
@@ -66,36 +140,70 @@ func (s *Service) GetVersion() string {
 	return s.Version
 }
 
+// parse the environment variables of the environment. references to other
+// services are only resolved by the CloudApplicationLayerConfig equivalent
 func (s *Service) ParseEnvironmentVariables(envName string) error {
+	return s.InterpolateEnvironmentVariables(&Interpolation{Environment: envName})
+}
+
+// interpolate the environment variables and secrets of the environment
+// every unresolved reference is reported in a single error
+func (s *Service) InterpolateEnvironmentVariables(interpolation *Interpolation) error {
+	envName := interpolation.Environment
 	if s.envVars == nil {
 		s.envVars = make(map[string]map[string]string)
 	}
 
+	// the parsed variables are updated in place since the rendered
+	// helm values keep referencing them once terraform outputs are set
 	if s.envVars[envName] == nil {
 		s.envVars[envName] = make(map[string]string)
 	}
 
 	// iterate through each environment variable
-	for key, value := range s.GetRawEnvironmentVariables() {
+	unresolved := []string{}
+	for _, key := range sortedKeys(s.GetRawEnvironmentVariables()) {
+		value := s.EnvironmentVariables[key]
+		// values of secret looking keys are masked in the logs
+		if logger.IsSecretKey(key) {
+			logger.RegisterSensitive(value)
+		}
+
 		logger.Debugf("converting env variables - key: %s, value: %s", key, value)
-		// check if the value is in the following format ${ENV_VAR}
-		if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
-			// get the environment variable name
-			envVar := value[2 : len(value)-1]
-			// get the environment variable value
-			envValue := os.Getenv(envVar)
-			// check if the environment variable value is empty
-			if envValue == "" {
-				return fmt.Errorf("environment variable %s is not set", envVar)
-			}
-			// update the environment variable value
-			s.envVars[envName][key] = envValue
-		} else {
-			// update the environment variable value
-			s.envVars[envName][key] = value
+		envValue, err := interpolation.parse(value)
+		if unresolved, err = collectUnresolved(unresolved, "environment."+key, err); err != nil {
+			return err
 		}
+		s.envVars[envName][key] = envValue
+	}
+
+	// the secret values are always masked in the logs
+	if s.secretVars == nil {
+		s.secretVars = make(map[string]map[string]string)
 	}
+	s.secretVars[envName] = make(map[string]string)
+	for _, key := range sortedKeys(s.Secrets) {
+		value := s.Secrets[key]
+		if _, ok := s.EnvironmentVariables[key]; ok {
+			return fmt.Errorf("the variable %s is defined in both environment and secrets", key)
+		}
+
+		// the secret is created before terraform runs
+		if interpolation.hasDeferredReferences(value) {
+			return fmt.Errorf("the secret %s can't reference terraform outputs", key)
+		}
 
+		secretValue, err := interpolation.parse(value)
+		if unresolved, err = collectUnresolved(unresolved, "secrets."+key, err); err != nil {
+			return err
+		}
+		logger.RegisterSensitive(secretValue)
+		s.secretVars[envName][key] = secretValue
+	}
+
+	if len(unresolved) > 0 {
+		return &UnresolvedReferencesError{References: unresolved}
+	}
 	return nil
 }
 
@@ -104,16 +212,38 @@ func (s *Service) GetRawEnvironmentVariables() map[string]string {
 	return s.EnvironmentVariables
 }
 
+// return true if the variable is defined by the environment or the secrets
+func (s *Service) DefinesVariable(key string) bool {
+	_, isVariable := s.EnvironmentVariables[key]
+	_, isSecret := s.Secrets[key]
+	return isVariable || isSecret
+}
+
 // return the parsed environment variables
 func (s *Service) GetEnvironmentVariables(envName string) map[string]string {
 	return s.envVars[envName]
 }
 
+// return the parsed secret variables
+func (s *Service) GetSecrets(envName string) map[string]string {
+	return s.secretVars[envName]
+}
+
 // return the replicas
 func (s *Service) GetReplicas() int {
 	return s.Replicas
 }
 
+// return the service resources
+func (s *Service) GetResources() *Resources {
+	return s.Resources
+}
+
+// return the service autoscaling configs
+func (s *Service) GetAutoscaling() *Autoscaling {
+	return s.Autoscaling
+}
+
 // return the health check url or /status
 func (s *Service) GetHealthCheckURL() string {
 	if s.HealthCheckURL == "" {
@@ -122,3 +252,46 @@ func (s *Service) GetHealthCheckURL() string {
 
 	return s.HealthCheckURL
 }
+
+// return the service probes
+func (s *Service) GetProbes() *Probes {
+	return s.Probes
+}
+
+// return the rollout timeout or the default rollout timeout
+func (s *Service) GetRolloutTimeout() (time.Duration, error) {
+	if s.RolloutTimeout == "" {
+		return DefaultRolloutTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(s.RolloutTimeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid rollout_timeout %s - expected a duration, e.g., 10m", s.RolloutTimeout)
+	}
+
+	return timeout, nil
+}
+
+// return true if the service should be rolled back on failure
+func (s *Service) IsAtomic(global bool) bool {
+	if s.Atomic == nil {
+		return global
+	}
+
+	return *s.Atomic
+}
+
+// return the rollout strategy
+func (s *Service) GetStrategy() *Strategy {
+	return s.Strategy
+}
+
+// return the service hooks
+func (s *Service) GetHooks() *ServiceHooks {
+	return s.Hooks
+}
+
+// return the services and databases this service depends on
+func (s *Service) GetDependencies() []string {
+	return s.DependsOn
+}
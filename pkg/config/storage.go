@@ -1,10 +1,7 @@
 package config
 
 import (
-	"bytes"
 	"context"
-	"crypto/md5"
-	"encoding/gob"
 	"fmt"
 	"os"
 	"time"
@@ -12,9 +9,14 @@ import (
 	helmclient "github.com/mittwald/go-helm-client"
 	"github.com/salfatigroup/nopeus/cli/util"
 	"github.com/salfatigroup/nopeus/helm"
+	"github.com/salfatigroup/nopeus/kubernetes"
 	"github.com/salfatigroup/nopeus/logger"
 )
 
+// the time to wait for a database to become ready, provisioning
+// the database volumes can take a while on a new cluster
+const databaseRolloutTimeout = 15 * time.Minute
+
 // define the storage config for the cluster
 type Storage struct {
 	// database configs
@@ -31,6 +33,13 @@ type DatabaseStorage struct {
 
 	// the database version
 	Version string `yaml:"version"`
+
+	// the services that must be ready before the database
+	DependsOn []string `yaml:"depends_on"`
+
+	// the services that are injected with the connection variables
+	// of the database, e.g., MAIN_DATABASE_URL. defaults to all the services
+	ExposeTo []string `yaml:"expose_to"`
 }
 
 // return one of the supported defalt database storage types
@@ -54,6 +63,7 @@ type NopeusStorageMicroservice struct {
 	Values         *HelmRendererValues `yaml:"values"`
 	Namespace      string              `yaml:"namespace"`
 	dryRun         bool                `yaml:"dry_run"`
+	dependsOn      []string
 }
 
 // implement the getname function
@@ -122,21 +132,56 @@ func (n *NopeusStorageMicroservice) ApplyHelmChart(kubeContext string) error {
 		return err
 	}
 
+	// generate the database credentials before the first install
+	// the chart reads them from the existing secret
+	if err := n.EnsureCredentials(kubeContext); err != nil {
+		return err
+	}
+
 	// apply db only once
 	if release, _ := helmClient.GetChartByName(n.GetName()); release != nil {
 		logger.Debugf("service %s already applied. skipping to avoid password changes", n.GetName())
 		return nil
 	}
 
-	fmt.Println(util.GrayText("Applying helm chart for service " + n.GetName()))
+	util.Emit(&util.Event{Type: util.EventMessage, Service: n.GetName(), Message: "Applying helm chart for service " + n.GetName()})
 
 	// install the chart
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Minute*15))
 	defer cancel()
-	if _, err := helmClient.Client.InstallOrUpgradeChart(ctx, chartSpec, nil); err != nil {
+	release, err := helmClient.Client.InstallOrUpgradeChart(ctx, chartSpec, nil)
+	if err != nil {
 		return err
 	}
 
+	// wait for the database pods to become ready
+	if n.dryRun {
+		return nil
+	}
+	util.Emit(&util.Event{Type: util.EventMessage, Service: n.GetName(), Message: "Waiting for service " + n.GetName() + " to become ready"})
+	return helm.WaitForRollout(release, kubeContext, databaseRolloutTimeout)
+}
+
+// create the secret of the database credentials unless it exists. the
+// services exposed to the database read the password from the same secret
+func (n *NopeusStorageMicroservice) EnsureCredentials(kubeContext string) error {
+	if n.dryRun {
+		return nil
+	}
+
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err != nil {
+		return err
+	}
+
+	created, err := EnsureDatabaseCredentials(context.Background(), client, n.Namespace, n.GetName())
+	if err != nil {
+		return fmt.Errorf("failed to create the credentials of database %s: %w", n.GetName(), err)
+	}
+	if created {
+		logger.Debugf("generated the credentials of database %s", n.GetName())
+	}
+
 	return nil
 }
 
@@ -160,12 +205,15 @@ func (n *NopeusStorageMicroservice) DeleteHelmChart(kubeContext string) error {
 	return helmClient.Client.UninstallRelease(chartSpec)
 }
 
+// implement the get dependencies function
+func (n *NopeusStorageMicroservice) GetDependencies() []string {
+	return n.dependsOn
+}
+
 // implement the get checksum function
 func (n *NopeusStorageMicroservice) GetChecksum() (string, error) {
-	var b bytes.Buffer
-	if err := gob.NewEncoder(&b).Encode(n); err != nil {
-		return "", err
-	}
-	md5sum := md5.Sum(b.Bytes())
-	return fmt.Sprintf("%x", md5sum), nil
+	return checksum(&struct {
+		*NopeusStorageMicroservice
+		DependsOn []string
+	}{n, n.dependsOn})
 }
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// define the supported rollout strategies
+const (
+	// upgrade the service deployment in place
+	StrategyRolling = "rolling"
+
+	// deploy the new version next to the current one and
+	// switch all the traffic at once after verifying it
+	StrategyBlueGreen = "blue-green"
+
+	// shift the traffic to the new version step by step
+	StrategyCanary = "canary"
+)
+
+// the ratio of failing requests that rolls the new version back by default
+const defaultErrorThreshold = 0.05
+
+// define how a new version of the service is rolled out
+type Strategy struct {
+	// the strategy type - rolling (default), blue-green or canary
+	Type string `yaml:"type"`
+
+	// the traffic steps of a canary rollout
+	Steps []*CanaryStep `yaml:"steps"`
+
+	// how long the new version is verified before and after
+	// the traffic is switched in a blue-green rollout, e.g., 5m
+	Pause string `yaml:"pause"`
+
+	// the ratio of failing requests to the new version, e.g., 0.05,
+	// that rolls the new version back - defaults to 5%
+	ErrorThreshold float64 `yaml:"error_threshold"`
+}
+
+// define a single canary traffic step
+type CanaryStep struct {
+	// the percentage of the traffic sent to the new version
+	Weight int `yaml:"weight"`
+
+	// how long the new version is verified before the next step, e.g., 2m
+	Pause string `yaml:"pause"`
+}
+
+// define a parsed traffic step of a progressive rollout
+type RolloutStep struct {
+	Weight int
+	Pause  time.Duration
+}
+
+// return the strategy type or the default rolling strategy
+func (s *Strategy) GetType() string {
+	if s == nil || s.Type == "" {
+		return StrategyRolling
+	}
+
+	return s.Type
+}
+
+// returns true if the new version is rolled out next to the current one
+func (s *Strategy) IsProgressive() bool {
+	return s.GetType() != StrategyRolling
+}
+
+// return the error threshold or the default error threshold
+func (s *Strategy) GetErrorThreshold() float64 {
+	if s == nil || s.ErrorThreshold == 0 {
+		return defaultErrorThreshold
+	}
+
+	return s.ErrorThreshold
+}
+
+// validate the strategy configs
+func (s *Strategy) Validate() error {
+	if s.GetErrorThreshold() < 0 || s.GetErrorThreshold() > 1 {
+		return fmt.Errorf("invalid strategy error_threshold %v - expected a ratio between 0 and 1", s.ErrorThreshold)
+	}
+
+	_, err := s.GetSteps()
+	return err
+}
+
+// return the traffic steps of the rollout
+func (s *Strategy) GetSteps() ([]*RolloutStep, error) {
+	switch s.GetType() {
+	case StrategyRolling:
+		return nil, nil
+	case StrategyBlueGreen:
+		pause, err := parsePause(s.Pause)
+		if err != nil {
+			return nil, err
+		}
+
+		// verify the new version before and after switching the traffic
+		return []*RolloutStep{{Weight: 0, Pause: pause}, {Weight: 100, Pause: pause}}, nil
+	case StrategyCanary:
+		if len(s.Steps) == 0 {
+			return nil, fmt.Errorf("canary strategy requires at least one step")
+		}
+
+		steps := []*RolloutStep{}
+		previous := 0
+		for _, step := range s.Steps {
+			if step.Weight <= previous || step.Weight > 100 {
+				return nil, fmt.Errorf("invalid canary step weight %d - expected increasing weights between 1 and 100", step.Weight)
+			}
+			previous = step.Weight
+
+			pause, err := parsePause(step.Pause)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, &RolloutStep{Weight: step.Weight, Pause: pause})
+		}
+
+		return steps, nil
+	default:
+		return nil, fmt.Errorf("unsupported strategy %s - use %s, %s or %s", s.Type, StrategyRolling, StrategyBlueGreen, StrategyCanary)
+	}
+}
+
+// parse the pause duration of a step
+func parsePause(pause string) (time.Duration, error) {
+	if pause == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(pause)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid strategy pause %s - expected a duration, e.g., 2m", pause)
+	}
+
+	return duration, nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestStrategySteps converts each strategy into its traffic steps
+func TestStrategySteps(t *testing.T) {
+	canary := &Strategy{
+		Type: StrategyCanary,
+		Steps: []*CanaryStep{
+			{Weight: 10, Pause: "1m"},
+			{Weight: 50, Pause: "2m"},
+			{Weight: 100},
+		},
+	}
+	steps, err := canary.GetSteps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []RolloutStep{{10, time.Minute}, {50, 2 * time.Minute}, {100, 0}}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %d", len(expected), len(steps))
+	}
+	for i, step := range steps {
+		if *step != expected[i] {
+			t.Errorf("expected step %d to be %+v, got %+v", i, expected[i], *step)
+		}
+	}
+
+	blueGreen := &Strategy{Type: StrategyBlueGreen, Pause: "5m"}
+	steps, err = blueGreen.GetSteps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].Weight != 0 || steps[1].Weight != 100 || steps[1].Pause != 5*time.Minute {
+		t.Errorf("expected blue-green to verify before and after switching, got %+v %+v", *steps[0], *steps[1])
+	}
+
+	var rolling *Strategy
+	if rolling.IsProgressive() {
+		t.Errorf("expected the default strategy to be rolling")
+	}
+}
+
+// TestStrategyValidate rejects invalid strategies
+func TestStrategyValidate(t *testing.T) {
+	for name, strategy := range map[string]*Strategy{
+		"unknown type":         {Type: "shadow"},
+		"canary without steps": {Type: StrategyCanary},
+		"decreasing weights":   {Type: StrategyCanary, Steps: []*CanaryStep{{Weight: 50}, {Weight: 20}}},
+		"weight above 100":     {Type: StrategyCanary, Steps: []*CanaryStep{{Weight: 120}}},
+		"invalid pause":        {Type: StrategyBlueGreen, Pause: "soon"},
+		"invalid threshold":    {Type: StrategyBlueGreen, ErrorThreshold: 2},
+	} {
+		if err := strategy.Validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/salfatigroup/nopeus/logger"
+)
+
+// define the telemetry configs. nopeus sends anonymous usage
+// events to help improve the product unless turned off
+type TelemetryConfig struct {
+	// turn the telemetry on/off - defaults to on
+	// NOPEUS_TELEMETRY=off always turns the telemetry off
+	Enabled *bool `yaml:"enabled"`
+
+	// the sink to send the events to - logsnag (default), file, http or none
+	Sink string `yaml:"sink"`
+
+	// the file the events are written to when using the file sink
+	// relative to the nopeus config
+	Path string `yaml:"path"`
+
+	// the endpoint the events are posted to when using the http sink
+	URL string `yaml:"url"`
+}
+
+// return true unless the telemetry is explicitly turned off
+func (t *TelemetryConfig) IsEnabled() bool {
+	if t == nil || t.Enabled == nil {
+		return true
+	}
+
+	return *t.Enabled
+}
+
+// configure the logger telemetry sink based on the telemetry configs
+func (t *TelemetryConfig) Apply(configPath string) error {
+	options := logger.TelemetryOptions{Enabled: t.IsEnabled()}
+	if t != nil {
+		options.Sink = t.Sink
+		options.URL = t.URL
+		options.Path = t.Path
+		if options.Path != "" && !filepath.IsAbs(options.Path) {
+			options.Path = filepath.Join(filepath.Dir(configPath), options.Path)
+		}
+	}
+
+	return logger.ConfigureTelemetry(options)
+}
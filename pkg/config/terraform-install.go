@@ -0,0 +1,289 @@
+package config
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/salfatigroup/nopeus/logger"
+	"golang.org/x/crypto/openpgp"
+)
+
+// the official terraform releases location
+var TerraformReleasesURL = "https://releases.hashicorp.com/terraform"
+
+// define the terraform installer that resolves a pinned
+// terraform version into a verified binary
+type terraformInstaller struct {
+	// the terraform version to install
+	version string
+
+	// a local directory mirroring the terraform releases
+	mirror string
+
+	// a pinned sha256 checksum of the release archive
+	checksum string
+
+	// the armored pgp key the release checksums file is signed with
+	signingKey string
+
+	// the directory the binaries are cached in
+	binDir string
+}
+
+// return the name of the release archive for the current platform
+func (t *terraformInstaller) archiveName() string {
+	return fmt.Sprintf("terraform_%s_%s_%s.zip", t.version, runtime.GOOS, runtime.GOARCH)
+}
+
+// return the name of the release checksums file
+func (t *terraformInstaller) checksumsName() string {
+	return fmt.Sprintf("terraform_%s_SHA256SUMS", t.version)
+}
+
+// return the name of the signature of the release checksums file
+func (t *terraformInstaller) signatureName() string {
+	return t.checksumsName() + ".sig"
+}
+
+// return the directory of the cached version
+func (t *terraformInstaller) versionDir() string {
+	return filepath.Join(t.binDir, "terraform_"+t.version)
+}
+
+// install the terraform version if required and return the path to the binary
+func (t *terraformInstaller) install() (string, error) {
+	dir := t.versionDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// the binary is only written once its archive is verified
+	execPath := filepath.Join(dir, "terraform")
+	if runtime.GOOS == "windows" {
+		execPath += ".exe"
+	}
+	if _, err := os.Stat(execPath); err == nil {
+		return execPath, nil
+	}
+
+	// make sure the release archive is cached
+	archivePath := filepath.Join(dir, t.archiveName())
+	if err := t.fetch(t.archiveName(), archivePath); err != nil {
+		return "", err
+	}
+
+	// verify the archive against the expected checksum
+	expected, err := t.expectedChecksum()
+	if err != nil {
+		return "", err
+	}
+	actual, err := sha256File(archivePath)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(expected, actual) {
+		// remove the archive to force a new download on the next run
+		os.Remove(archivePath)
+		return "", fmt.Errorf("terraform %s checksum mismatch - expected %s, got %s", t.version, expected, actual)
+	}
+
+	// extract the binary from the verified archive
+	if err := extractTerraform(archivePath, execPath); err != nil {
+		return "", err
+	}
+
+	return execPath, nil
+}
+
+// return the expected checksum of the release archive
+func (t *terraformInstaller) expectedChecksum() (string, error) {
+	if t.checksum != "" {
+		return t.checksum, nil
+	}
+
+	// the checksums come from the same origin as the archive
+	// so they are only trusted once their signature is verified
+	if t.signingKey == "" {
+		return "", fmt.Errorf("the terraform signing_key is required to verify %s unless the sha256 of the release is pinned", t.checksumsName())
+	}
+
+	checksumsPath := filepath.Join(t.versionDir(), t.checksumsName())
+	if err := t.fetch(t.checksumsName(), checksumsPath); err != nil {
+		return "", err
+	}
+	signaturePath := filepath.Join(t.versionDir(), t.signatureName())
+	if err := t.fetch(t.signatureName(), signaturePath); err != nil {
+		return "", err
+	}
+
+	checksums, err := os.ReadFile(checksumsPath)
+	if err != nil {
+		return "", err
+	}
+	if err := verifySignature(t.signingKey, checksums, signaturePath); err != nil {
+		// remove the files to force a new download on the next run
+		os.Remove(checksumsPath)
+		os.Remove(signaturePath)
+		return "", fmt.Errorf("failed to verify the signature of %s: %w", t.checksumsName(), err)
+	}
+
+	// each line is in the following format "<sha256>  <file name>"
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == t.archiveName() {
+			return fields[0], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("checksum of %s not found in %s", t.archiveName(), t.checksumsName())
+}
+
+// verify the detached signature of the content with the armored key
+func verifySignature(keyPath string, content []byte, signaturePath string) error {
+	key, err := os.Open(keyPath)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(key)
+	if err != nil {
+		return err
+	}
+
+	signature, err := os.Open(signaturePath)
+	if err != nil {
+		return err
+	}
+	defer signature.Close()
+
+	_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(content), signature)
+	return err
+}
+
+// fetch a release file from the mirror or the terraform releases
+// location unless it's already cached in the destination
+func (t *terraformInstaller) fetch(name string, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+
+	// look for the file in the local mirror first
+	// supports both flat mirrors and mirrors split by version
+	if t.mirror != "" {
+		for _, source := range []string{
+			filepath.Join(t.mirror, t.version, name),
+			filepath.Join(t.mirror, name),
+		} {
+			if _, err := os.Stat(source); err == nil {
+				logger.Debugf("Copying %s from the terraform mirror", source)
+				return copyFile(source, dest)
+			}
+		}
+
+		return fmt.Errorf("%s not found in terraform mirror %s", name, t.mirror)
+	}
+
+	url := fmt.Sprintf("%s/%s/%s", TerraformReleasesURL, t.version, name)
+	logger.Debugf("Downloading %s", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s - %s", url, resp.Status)
+	}
+
+	return writeToFile(dest, resp.Body, 0o644)
+}
+
+// extract the terraform binary from the release archive
+func extractTerraform(archivePath string, execPath string) error {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if file.Name != filepath.Base(execPath) {
+			continue
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+
+		return writeToFile(execPath, content, 0o755)
+	}
+
+	return fmt.Errorf("terraform binary not found in %s", archivePath)
+}
+
+// return the hex encoded sha256 of the given file
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copy a file to the given destination
+func copyFile(source string, dest string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeToFile(dest, file, 0o644)
+}
+
+// write the reader content to the given file
+// the content is written to a temp file first to avoid partial files
+func writeToFile(dest string, content io.Reader, perm os.FileMode) error {
+	tmp := dest + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, dest)
+}
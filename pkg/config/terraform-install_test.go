@@ -0,0 +1,190 @@
+package config
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// create a signing key and return the path to its armored public key
+func createSigningKey(t *testing.T) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity("releases", "", "releases@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key bytes.Buffer
+	writer, err := armor.Encode(&key, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "releases.asc")
+	if err := os.WriteFile(keyPath, key.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return entity, keyPath
+}
+
+// create a fake terraform release in the given mirror directory
+// with its checksums signed by the given key
+func createTerraformMirror(t *testing.T, mirror string, installer *terraformInstaller, signer *openpgp.Entity) {
+	archivePath := filepath.Join(mirror, installer.archiveName())
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := zip.NewWriter(file)
+	binary, err := archive.Create("terraform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := binary.Write([]byte("#!/bin/sh\necho terraform\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	checksums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), installer.archiveName())
+	if err := os.WriteFile(filepath.Join(mirror, installer.checksumsName()), []byte(checksums), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, signer, strings.NewReader(checksums), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mirror, installer.signatureName()), signature.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestInstallTerraformFromMirror installs terraform from a local mirror
+func TestInstallTerraformFromMirror(t *testing.T) {
+	signer, keyPath := createSigningKey(t)
+	mirror := t.TempDir()
+	installer := &terraformInstaller{
+		version:    "1.3.0",
+		mirror:     mirror,
+		signingKey: keyPath,
+		binDir:     t.TempDir(),
+	}
+	createTerraformMirror(t, mirror, installer, signer)
+
+	execPath, err := installer.install()
+	if err != nil {
+		t.Fatalf("error installing terraform: %s", err)
+	}
+
+	info, err := os.Stat(execPath)
+	if err != nil {
+		t.Fatalf("terraform binary not found: %s", err)
+	}
+	if info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("terraform binary is not executable: %s", info.Mode())
+	}
+}
+
+// TestInstallTerraformChecksumMismatch rejects a tampered release archive
+func TestInstallTerraformChecksumMismatch(t *testing.T) {
+	mirror := t.TempDir()
+	installer := &terraformInstaller{
+		version:  "1.3.0",
+		mirror:   mirror,
+		checksum: "0000000000000000000000000000000000000000000000000000000000000000",
+		binDir:   t.TempDir(),
+	}
+	signer, _ := createSigningKey(t)
+	createTerraformMirror(t, mirror, installer, signer)
+
+	if _, err := installer.install(); err == nil {
+		t.Errorf("expected a checksum mismatch error")
+	}
+
+	// the cached archive should be removed after a mismatch
+	if _, err := os.Stat(filepath.Join(installer.versionDir(), installer.archiveName())); !os.IsNotExist(err) {
+		t.Errorf("expected the mismatched archive to be removed")
+	}
+}
+
+// TestInstallTerraformSignature trusts the checksums only when signed by the signing key
+func TestInstallTerraformSignature(t *testing.T) {
+	signer, _ := createSigningKey(t)
+	_, otherKeyPath := createSigningKey(t)
+
+	for name, signingKey := range map[string]string{
+		"missing key": "",
+		"other key":   otherKeyPath,
+	} {
+		mirror := t.TempDir()
+		installer := &terraformInstaller{
+			version:    "1.3.0",
+			mirror:     mirror,
+			signingKey: signingKey,
+			binDir:     t.TempDir(),
+		}
+		createTerraformMirror(t, mirror, installer, signer)
+
+		if _, err := installer.install(); err == nil {
+			t.Errorf("%s: expected the checksums to be rejected", name)
+		}
+		if _, err := os.Stat(filepath.Join(installer.versionDir(), "terraform")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no terraform binary", name)
+		}
+	}
+}
+
+// TestInstallTerraformCached keeps the verified binary without extracting it again
+func TestInstallTerraformCached(t *testing.T) {
+	signer, keyPath := createSigningKey(t)
+	mirror := t.TempDir()
+	installer := &terraformInstaller{
+		version:    "1.3.0",
+		mirror:     mirror,
+		signingKey: keyPath,
+		binDir:     t.TempDir(),
+	}
+	createTerraformMirror(t, mirror, installer, signer)
+
+	execPath, err := installer.install()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a cached binary is used as is, even when the archive is gone
+	if err := os.Remove(filepath.Join(installer.versionDir(), installer.archiveName())); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(mirror); err != nil {
+		t.Fatal(err)
+	}
+	cachedPath, err := installer.install()
+	if err != nil || cachedPath != execPath {
+		t.Errorf("expected the cached binary %s, got %s (%v)", execPath, cachedPath, err)
+	}
+}
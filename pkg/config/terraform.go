@@ -0,0 +1,114 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	tmpl "text/template"
+)
+
+// define the terraform configs that control how nopeus
+// runs the generated terraform modules
+type TerraformConfig struct {
+	// the terraform backend to store the state in
+	// if not set, nopeus stores the state in its own nopeus state
+	Backend *TerraformBackend `yaml:"backend"`
+
+	// directories with user supplied terraform files, relative to the
+	// nopeus config, that are applied alongside the generated environment
+	ExtraDirs []string `yaml:"extra_dirs"`
+
+	// pin the terraform version to use. when set, nopeus installs the
+	// terraform binary into .nopeus/bin instead of using the one in PATH
+	Version string `yaml:"version"`
+
+	// a local directory mirroring the terraform releases
+	// used instead of downloading from releases.hashicorp.com
+	Mirror string `yaml:"mirror"`
+
+	// the expected sha256 of the terraform release archive
+	// defaults to the checksum in the release SHA256SUMS file
+	Checksum string `yaml:"sha256"`
+
+	// the armored pgp key the release SHA256SUMS file is signed with,
+	// relative to the nopeus config. required unless the sha256 is
+	// pinned, e.g., the hashicorp release key from hashicorp.com/security
+	SigningKey string `yaml:"signing_key"`
+}
+
+// define a terraform backend block
+// e.g., s3 with a dynamodb lock table
+type TerraformBackend struct {
+	// the backend type (s3, gcs, azurerm, remote, ...)
+	Type string `yaml:"type"`
+
+	// the backend configuration passed to terraform init
+	// values can use {{ .Environment }} and {{ .Name }} to
+	// separate the state of each environment
+	Config map[string]string `yaml:"config"`
+}
+
+// return the terraform backend or nil if the state is managed by nopeus
+func (t *TerraformConfig) GetBackend() *TerraformBackend {
+	if t == nil || t.Backend == nil || t.Backend.Type == "" {
+		return nil
+	}
+
+	return t.Backend
+}
+
+// returns true if the terraform state is stored in a remote backend
+func (t *TerraformConfig) HasRemoteBackend() bool {
+	return t.GetBackend() != nil
+}
+
+// return the user supplied terraform directories
+func (t *TerraformConfig) GetExtraDirs() []string {
+	if t == nil {
+		return nil
+	}
+
+	return t.ExtraDirs
+}
+
+// return the pinned terraform version or an empty string to use terraform from PATH
+func (t *TerraformConfig) GetVersion() string {
+	if t == nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(t.Version, "v")
+}
+
+// return the backend configs as key=value pairs rendered for the given environment
+func (b *TerraformBackend) GetBackendConfig(name string, envName string) ([]string, error) {
+	values := map[string]string{
+		"Environment": envName,
+		"Name":        name,
+	}
+
+	// sort the keys to keep terraform init args stable
+	keys := make([]string, 0, len(b.Config))
+	for key := range b.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	backendConfig := make([]string, 0, len(keys))
+	for _, key := range keys {
+		t, err := tmpl.New(key).Option("missingkey=error").Parse(b.Config[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid terraform backend config %s: %w", key, err)
+		}
+
+		var rendered bytes.Buffer
+		if err := t.Execute(&rendered, values); err != nil {
+			return nil, fmt.Errorf("invalid terraform backend config %s: %w", key, err)
+		}
+
+		backendConfig = append(backendConfig, fmt.Sprintf("%s=%s", key, rendered.String()))
+	}
+
+	return backendConfig, nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestTerraformBackend only treats a backend with a type as remote
+func TestTerraformBackend(t *testing.T) {
+	for name, test := range map[string]struct {
+		terraform *TerraformConfig
+		remote    bool
+	}{
+		"no terraform config": {terraform: nil, remote: false},
+		"no backend":          {terraform: &TerraformConfig{}, remote: false},
+		"backend without type": {
+			terraform: &TerraformConfig{Backend: &TerraformBackend{Config: map[string]string{"bucket": "state"}}},
+			remote:    false,
+		},
+		"s3 backend": {terraform: &TerraformConfig{Backend: &TerraformBackend{Type: "s3"}}, remote: true},
+	} {
+		if remote := test.terraform.HasRemoteBackend(); remote != test.remote {
+			t.Errorf("%s: expected remote backend to be %v, got %v", name, test.remote, remote)
+		}
+		if backend := test.terraform.GetBackend(); (backend != nil) != test.remote {
+			t.Errorf("%s: expected a backend only for remote state, got %v", name, backend)
+		}
+	}
+}
+
+// TestGetBackendConfig renders the backend configs per environment
+func TestGetBackendConfig(t *testing.T) {
+	for name, test := range map[string]struct {
+		config   map[string]string
+		expected []string
+		fails    bool
+	}{
+		"empty": {config: nil, expected: []string{}},
+		"sorted keys": {
+			config:   map[string]string{"region": "us-east-1", "bucket": "state", "dynamodb_table": "locks"},
+			expected: []string{"bucket=state", "dynamodb_table=locks", "region=us-east-1"},
+		},
+		"templated values": {
+			config:   map[string]string{"key": "{{ .Name }}/{{ .Environment }}/terraform.tfstate"},
+			expected: []string{"key=shop/prod/terraform.tfstate"},
+		},
+		"invalid template": {config: map[string]string{"key": "{{ .Name"}, fails: true},
+		"unknown value":    {config: map[string]string{"key": "{{ .Region.Name }}"}, fails: true},
+	} {
+		backend := &TerraformBackend{Type: "s3", Config: test.config}
+		backendConfig, err := backend.GetBackendConfig("shop", "prod")
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", name, backendConfig)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(backendConfig, test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, backendConfig)
+		}
+	}
+}
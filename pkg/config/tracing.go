@@ -0,0 +1,37 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/salfatigroup/nopeus/tracing"
+)
+
+// define where the liftoff traces are exported to. the standard
+// OTEL_EXPORTER_OTLP_* environment variables are supported as well
+type TracingConfig struct {
+	// the otlp http endpoint, e.g., http://localhost:4318
+	Endpoint string `yaml:"endpoint"`
+
+	// additional headers sent to the otlp endpoint
+	Headers map[string]string `yaml:"headers"`
+
+	// a local file the spans are written to as json,
+	// relative to the nopeus config
+	File string `yaml:"file"`
+}
+
+// initialize the tracer based on the tracing configs
+func (t *TracingConfig) Apply(ctx context.Context, configPath string) error {
+	options := tracing.Options{}
+	if t != nil {
+		options.Endpoint = t.Endpoint
+		options.Headers = t.Headers
+		options.File = t.File
+		if options.File != "" && !filepath.IsAbs(options.File) {
+			options.File = filepath.Join(filepath.Dir(configPath), options.File)
+		}
+	}
+
+	return tracing.Init(ctx, options)
+}
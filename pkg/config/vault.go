@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resolve the env values from hashicorp vault, e.g., vault://kv/data/api#db_password
+// authenticates by token or approle, configured by the standard vault env variables
+type VaultResolver struct {
+	// the vault address, defaults to $VAULT_ADDR
+	Address string
+
+	// the vault token, defaults to $VAULT_TOKEN or ~/.vault-token
+	Token string
+
+	// the approle credentials used without a token
+	// default to $VAULT_ROLE_ID and $VAULT_SECRET_ID
+	RoleID   string
+	SecretID string
+
+	// the vault enterprise namespace, defaults to $VAULT_NAMESPACE
+	Namespace string
+
+	// the token of the approle login
+	loginToken string
+
+	// the secrets read so far by their path
+	secrets map[string]map[string]interface{}
+
+	// guards the login token and the secrets
+	mutex sync.Mutex
+}
+
+// define the vault response of a secret read or an approle login
+type vaultResponse struct {
+	Data map[string]interface{} `json:"data"`
+	Auth *struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// the vault client timeout
+const vaultTimeout = 10 * time.Second
+
+// return the vault address
+func (v *VaultResolver) GetAddress() string {
+	if v.Address == "" {
+		return strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	}
+
+	return strings.TrimSuffix(v.Address, "/")
+}
+
+// return the configured vault token if any
+func (v *VaultResolver) GetToken() string {
+	if v.Token != "" {
+		return v.Token
+	}
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token
+	}
+
+	// the token of the vault cli login
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
+// return the approle role id
+func (v *VaultResolver) GetRoleID() string {
+	if v.RoleID == "" {
+		return os.Getenv("VAULT_ROLE_ID")
+	}
+
+	return v.RoleID
+}
+
+// return the approle secret id
+func (v *VaultResolver) GetSecretID() string {
+	if v.SecretID == "" {
+		return os.Getenv("VAULT_SECRET_ID")
+	}
+
+	return v.SecretID
+}
+
+// return the vault namespace
+func (v *VaultResolver) GetNamespace() string {
+	if v.Namespace == "" {
+		return os.Getenv("VAULT_NAMESPACE")
+	}
+
+	return v.Namespace
+}
+
+// return the key of the secret at the path, e.g., kv/data/api#db_password
+func (v *VaultResolver) Resolve(reference string) (string, error) {
+	path, key, ok := strings.Cut(reference, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %s - expected <path>#<key>", reference)
+	}
+	if v.GetAddress() == "" {
+		return "", fmt.Errorf("the vault address is not set - set VAULT_ADDR")
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	// each path is read once for all of its keys
+	secret, ok := v.secrets[path]
+	if !ok {
+		var err error
+		secret, err = v.read(path)
+		if err != nil {
+			return "", err
+		}
+		if v.secrets == nil {
+			v.secrets = make(map[string]map[string]interface{})
+		}
+		v.secrets[path] = secret
+	}
+
+	value, ok := secret[key]
+	if !ok {
+		return "", fmt.Errorf("the vault secret %s has no key %s", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	// non string values are passed as json, e.g., numbers and lists
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// read the secret at the path, unwrapping the data of kv version 2 secrets
+func (v *VaultResolver) read(path string) (map[string]interface{}, error) {
+	token, err := v.token()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := v.request("GET", path, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// kv version 2 nests the secret under data next to its metadata
+	if data, ok := response.Data["data"].(map[string]interface{}); ok {
+		if _, ok := response.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+
+	return response.Data, nil
+}
+
+// return the configured token or login with the approle credentials
+func (v *VaultResolver) token() (string, error) {
+	if token := v.GetToken(); token != "" {
+		return token, nil
+	}
+	if v.loginToken != "" {
+		return v.loginToken, nil
+	}
+	if v.GetRoleID() == "" || v.GetSecretID() == "" {
+		return "", fmt.Errorf("no vault credentials found - set VAULT_TOKEN or VAULT_ROLE_ID and VAULT_SECRET_ID")
+	}
+
+	response, err := v.request("POST", "auth/approle/login", "", map[string]string{
+		"role_id":   v.GetRoleID(),
+		"secret_id": v.GetSecretID(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("vault approle login failed: %w", err)
+	}
+	if response.Auth == nil || response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault approle login returned no token")
+	}
+
+	v.loginToken = response.Auth.ClientToken
+	return v.loginToken, nil
+}
+
+// send a request to the vault api
+func (v *VaultResolver) request(method string, path string, token string, body interface{}) (*vaultResponse, error) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", v.GetAddress(), strings.TrimPrefix(path, "/")), &payload)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace := v.GetNamespace(); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: vaultTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &vaultResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil && resp.StatusCode < 300 {
+		return nil, fmt.Errorf("invalid vault response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		if len(response.Errors) > 0 {
+			return nil, fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(response.Errors, ", "))
+		}
+		return nil, fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/salfatigroup/nopeus/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// define the supported volume types
+const (
+	// a disk that outlives the pods of the service
+	VolumeTypePersistent = "persistent"
+
+	// a scratch directory that lives as long as the pod
+	VolumeTypeEmptyDir = "emptydir"
+
+	// the keys of an existing configmap as files
+	VolumeTypeConfigMap = "configmap"
+
+	// the keys of an existing secret as files
+	VolumeTypeSecret = "secret"
+)
+
+// define the supported persistent volume access modes
+const (
+	// mounted by a single node, each replica gets its own disk
+	AccessModeReadWriteOnce = "read-write-once"
+
+	// mounted by all the replicas
+	AccessModeReadWriteMany = "read-write-many"
+
+	// mounted read only by all the replicas
+	AccessModeReadOnlyMany = "read-only-many"
+)
+
+// the storage class used by each cloud vendor when none is set
+var defaultStorageClasses = map[string]string{
+	"aws": "gp2",
+}
+
+// kubernetes volume names are lowercase dns labels
+var volumeNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// define a volume mounted into the service containers
+type Volume struct {
+	// the volume name, e.g., data
+	Name string `yaml:"name"`
+
+	// the volume type - persistent, emptydir, configmap or secret
+	// defaults to persistent
+	Type string `yaml:"type"`
+
+	// where the volume is mounted in the container, e.g., /data
+	MountPath string `yaml:"mount_path"`
+
+	// mount the volume read only
+	ReadOnly bool `yaml:"read_only"`
+
+	// the disk size of a persistent volume or the
+	// size limit of an emptydir volume, e.g., 10Gi
+	Size string `yaml:"size"`
+
+	// the storage class of a persistent volume
+	// defaults to the cloud vendor default storage class
+	StorageClass string `yaml:"storage_class"`
+
+	// how a persistent volume is mounted - read-write-once,
+	// read-write-many or read-only-many. defaults to read-write-once
+	// a read-write-once volume runs the service as a stateful set with a disk
+	// per replica. kubernetes can't change the disks of a running stateful set,
+	// so adding, removing or changing such a volume later is rejected
+	AccessMode string `yaml:"access_mode"`
+
+	// the name of the configmap or secret to mount
+	Source string `yaml:"source"`
+
+	// mount a single key of the configmap or secret
+	SubPath string `yaml:"-"`
+
+	// create a disk for each replica of a stateful set
+	// instead of a single claim shared by the replicas
+	ClaimTemplate bool `yaml:"-"`
+}
+
+// return the volume type, defaults to persistent
+func (v *Volume) GetType() string {
+	if v.Type == "" {
+		return VolumeTypePersistent
+	}
+
+	return v.Type
+}
+
+// return the volume access mode, defaults to read-write-once
+func (v *Volume) GetAccessMode() string {
+	if v.AccessMode == "" {
+		return AccessModeReadWriteOnce
+	}
+
+	return v.AccessMode
+}
+
+// return the kubernetes access mode of the volume, e.g., ReadWriteOnce
+func (v *Volume) GetKubernetesAccessMode() string {
+	switch v.GetAccessMode() {
+	case AccessModeReadWriteMany:
+		return "ReadWriteMany"
+	case AccessModeReadOnlyMany:
+		return "ReadOnlyMany"
+	default:
+		return "ReadWriteOnce"
+	}
+}
+
+// validate the volume configs based on the volume type
+func (v *Volume) Validate() error {
+	if !volumeNameRegexp.MatchString(v.Name) {
+		return fmt.Errorf("invalid volume name %q - expected lowercase letters, numbers and dashes", v.Name)
+	}
+	if !path.IsAbs(v.MountPath) {
+		return fmt.Errorf("the volume %s requires an absolute mount_path", v.Name)
+	}
+
+	switch v.GetType() {
+	case VolumeTypePersistent:
+		if _, err := resource.ParseQuantity(v.Size); err != nil {
+			return fmt.Errorf("the volume %s requires a size, e.g., 10Gi", v.Name)
+		}
+		switch v.GetAccessMode() {
+		case AccessModeReadWriteOnce, AccessModeReadWriteMany, AccessModeReadOnlyMany:
+		default:
+			return fmt.Errorf("invalid volume %s access_mode %s - expected %s, %s or %s", v.Name, v.AccessMode, AccessModeReadWriteOnce, AccessModeReadWriteMany, AccessModeReadOnlyMany)
+		}
+		if v.Source != "" {
+			return fmt.Errorf("the persistent volume %s can't have a source", v.Name)
+		}
+
+	case VolumeTypeEmptyDir:
+		if v.Size != "" {
+			if _, err := resource.ParseQuantity(v.Size); err != nil {
+				return fmt.Errorf("invalid volume %s size %s - expected a quantity, e.g., 1Gi", v.Name, v.Size)
+			}
+		}
+		if v.StorageClass != "" || v.AccessMode != "" || v.Source != "" {
+			return fmt.Errorf("the emptydir volume %s only supports a size", v.Name)
+		}
+
+	case VolumeTypeConfigMap, VolumeTypeSecret:
+		if v.Source == "" {
+			return fmt.Errorf("the %s volume %s requires a source", v.GetType(), v.Name)
+		}
+		if v.Size != "" || v.StorageClass != "" || v.AccessMode != "" {
+			return fmt.Errorf("the %s volume %s only supports a source", v.GetType(), v.Name)
+		}
+
+	default:
+		return fmt.Errorf("invalid volume %s type %s - expected %s, %s, %s or %s", v.Name, v.Type, VolumeTypePersistent, VolumeTypeEmptyDir, VolumeTypeConfigMap, VolumeTypeSecret)
+	}
+
+	return nil
+}
+
+// validate the volumes have unique names and mount paths
+func ValidateVolumes(volumes []*Volume) error {
+	names := make(map[string]bool)
+	mountPaths := make(map[string]bool)
+	for _, volume := range volumes {
+		if err := volume.Validate(); err != nil {
+			return err
+		}
+
+		if names[volume.Name] {
+			return fmt.Errorf("the volume %s is defined more than once", volume.Name)
+		}
+		names[volume.Name] = true
+
+		if mountPaths[path.Clean(volume.MountPath)] {
+			return fmt.Errorf("more than one volume is mounted to %s", volume.MountPath)
+		}
+		mountPaths[path.Clean(volume.MountPath)] = true
+	}
+
+	return nil
+}
+
+// return a copy of the volumes with the defaults of the cloud vendor.
+// with claim templates, each replica gets its own read-write-once disk
+func resolveVolumes(volumes []*Volume, cloudVendor string, claimTemplates bool) []*Volume {
+	resolved := []*Volume{}
+	for _, volume := range volumes {
+		v := *volume
+		v.Type = volume.GetType()
+		if v.Type == VolumeTypePersistent {
+			v.AccessMode = volume.GetAccessMode()
+			if v.StorageClass == "" {
+				v.StorageClass = defaultStorageClasses[cloudVendor]
+			}
+			v.ClaimTemplate = claimTemplates && v.AccessMode == AccessModeReadWriteOnce
+		}
+
+		resolved = append(resolved, &v)
+	}
+
+	return resolved
+}
+
+// return true if any of the volumes is created per replica
+func hasClaimTemplates(volumes []*Volume) bool {
+	for _, volume := range volumes {
+		if volume.ClaimTemplate {
+			return true
+		}
+	}
+
+	return false
+}
+
+// the kinds of workloads running a long running service
+const (
+	workloadKindDeployment  = "Deployment"
+	workloadKindStatefulSet = "StatefulSet"
+)
+
+// define the workload rendered for a long running service
+type workloadSpec struct {
+	// the workload kind - Deployment or StatefulSet
+	kind string
+
+	// the volumes created per replica of a stateful set
+	claimTemplates []*Volume
+}
+
+// return the workload running a service with the given resolved volumes
+func newWorkloadSpec(volumes []*Volume) *workloadSpec {
+	workload := &workloadSpec{kind: workloadKindDeployment}
+	for _, volume := range volumes {
+		if volume.ClaimTemplate {
+			workload.kind = workloadKindStatefulSet
+			workload.claimTemplates = append(workload.claimTemplates, volume)
+		}
+	}
+
+	return workload
+}
+
+// validate the workload of the service in the cluster can be upgraded in place
+func (w *workloadSpec) check(kubeContext string, namespace string, name string) error {
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err != nil {
+		return err
+	}
+
+	kind, claimTemplates, err := kubernetes.GetWorkloadKind(context.Background(), client, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	return w.validateTransition(kind, claimTemplates)
+}
+
+// validate the workload can replace the current one. helm would replace a
+// deployment with a stateful set, or the other way around, instead of
+// upgrading it, and kubernetes forbids changing the claim templates
+func (w *workloadSpec) validateTransition(current string, claimTemplates []corev1.PersistentVolumeClaim) error {
+	switch {
+	case current == "":
+		return nil
+	case current == workloadKindDeployment && w.kind == workloadKindStatefulSet:
+		return fmt.Errorf("the read-write-once volume %s would replace the deployment of the service with a stateful set - use a read-write-many volume or remove the service before adding it", w.claimTemplates[0].Name)
+	case current == workloadKindStatefulSet && w.kind == workloadKindDeployment:
+		return fmt.Errorf("removing the read-write-once volumes would replace the stateful set of the service with a deployment - remove the service before removing them")
+	case current != w.kind:
+		return nil
+	}
+
+	// the claim templates of a stateful set can't be changed once created
+	existing := make(map[string]corev1.PersistentVolumeClaim)
+	for _, claim := range claimTemplates {
+		existing[claim.Name] = claim
+	}
+	for _, volume := range w.claimTemplates {
+		claim, ok := existing[volume.Name]
+		if !ok {
+			return fmt.Errorf("the read-write-once volume %s can't be added to the stateful set of the service - claim templates can't be changed once created", volume.Name)
+		}
+		if !claimMatchesVolume(claim, volume) {
+			return fmt.Errorf("the read-write-once volume %s can't be changed - claim templates can't be changed once created", volume.Name)
+		}
+		delete(existing, volume.Name)
+	}
+	for _, claim := range claimTemplates {
+		if _, ok := existing[claim.Name]; ok {
+			return fmt.Errorf("the read-write-once volume %s can't be removed from the stateful set of the service - claim templates can't be changed once created", claim.Name)
+		}
+	}
+
+	return nil
+}
+
+// return true if the claim template has the size, storage
+// class and access mode of the volume
+func claimMatchesVolume(claim corev1.PersistentVolumeClaim, volume *Volume) bool {
+	storageClass := ""
+	if claim.Spec.StorageClassName != nil {
+		storageClass = *claim.Spec.StorageClassName
+	}
+	if storageClass != volume.StorageClass {
+		return false
+	}
+
+	if len(claim.Spec.AccessModes) != 1 || string(claim.Spec.AccessModes[0]) != volume.GetKubernetesAccessMode() {
+		return false
+	}
+
+	size, err := resource.ParseQuantity(volume.Size)
+	if err != nil {
+		return false
+	}
+	requested := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	return requested.Cmp(size) == 0
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestValidateVolumes checks the configs of each volume type
+func TestValidateVolumes(t *testing.T) {
+	valid := []*Volume{
+		{Name: "data", MountPath: "/data", Size: "10Gi"},
+		{Name: "cache", Type: VolumeTypeEmptyDir, MountPath: "/cache", Size: "1Gi"},
+		{Name: "nginx", Type: VolumeTypeConfigMap, MountPath: "/etc/nginx", Source: "nginx-config"},
+		{Name: "certs", Type: VolumeTypeSecret, MountPath: "/etc/certs", Source: "tls", ReadOnly: true},
+	}
+	if err := ValidateVolumes(valid); err != nil {
+		t.Errorf("expected valid volumes, got %v", err)
+	}
+
+	cases := map[string][]*Volume{
+		"invalid name":        {{Name: "Data", MountPath: "/data", Size: "1Gi"}},
+		"relative mount path": {{Name: "data", MountPath: "data", Size: "1Gi"}},
+		"missing size":        {{Name: "data", MountPath: "/data"}},
+		"invalid access mode": {{Name: "data", MountPath: "/data", Size: "1Gi", AccessMode: "sometimes"}},
+		"unknown type":        {{Name: "data", Type: "hostpath", MountPath: "/data"}},
+		"missing source":      {{Name: "nginx", Type: VolumeTypeConfigMap, MountPath: "/etc/nginx"}},
+		"emptydir class":      {{Name: "cache", Type: VolumeTypeEmptyDir, MountPath: "/cache", StorageClass: "gp2"}},
+		"duplicate name":      {{Name: "data", MountPath: "/a", Size: "1Gi"}, {Name: "data", MountPath: "/b", Size: "1Gi"}},
+		"duplicate mount":     {{Name: "a", MountPath: "/data", Size: "1Gi"}, {Name: "b", MountPath: "/data/", Size: "1Gi"}},
+	}
+	for name, volumes := range cases {
+		if err := ValidateVolumes(volumes); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestResolveVolumes fills the vendor defaults and picks the claim templates
+func TestResolveVolumes(t *testing.T) {
+	volumes := []*Volume{
+		{Name: "data", MountPath: "/data", Size: "10Gi"},
+		{Name: "shared", MountPath: "/shared", Size: "10Gi", AccessMode: AccessModeReadWriteMany, StorageClass: "efs"},
+		{Name: "cache", Type: VolumeTypeEmptyDir, MountPath: "/cache"},
+	}
+
+	resolved := resolveVolumes(volumes, "aws", true)
+	if resolved[0].StorageClass != "gp2" || !resolved[0].ClaimTemplate {
+		t.Errorf("expected a gp2 claim template, got %+v", *resolved[0])
+	}
+	if resolved[1].StorageClass != "efs" || resolved[1].ClaimTemplate {
+		t.Errorf("expected a shared efs claim, got %+v", *resolved[1])
+	}
+	if resolved[2].ClaimTemplate || resolved[2].StorageClass != "" {
+		t.Errorf("expected the emptydir volume to be left as is, got %+v", *resolved[2])
+	}
+	if volumes[0].StorageClass != "" {
+		t.Errorf("expected the configs not to be modified")
+	}
+
+	if hasClaimTemplates(resolveVolumes(volumes, "aws", false)) {
+		t.Errorf("expected no claim templates")
+	}
+}
+
+// TestWorkloadValidateTransition rejects the changes kubernetes can't apply in place
+func TestWorkloadValidateTransition(t *testing.T) {
+	volumes := resolveVolumes([]*Volume{
+		{Name: "data", MountPath: "/data", Size: "10Gi"},
+		{Name: "shared", MountPath: "/shared", Size: "10Gi", AccessMode: AccessModeReadWriteMany},
+	}, "aws", true)
+	statefulSet := newWorkloadSpec(volumes)
+	deployment := newWorkloadSpec(nil)
+
+	storageClass := "gp2"
+	claim := func(name string, size string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClass,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources:        corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)}},
+			},
+		}
+	}
+
+	if err := statefulSet.validateTransition("", nil); err != nil {
+		t.Errorf("expected a new workload to be valid, got %v", err)
+	}
+	if err := statefulSet.validateTransition(workloadKindStatefulSet, []corev1.PersistentVolumeClaim{claim("data", "10Gi")}); err != nil {
+		t.Errorf("expected unchanged claim templates to be valid, got %v", err)
+	}
+	if err := deployment.validateTransition(workloadKindDeployment, nil); err != nil {
+		t.Errorf("expected a deployment upgrade to be valid, got %v", err)
+	}
+
+	cases := map[string]struct {
+		workload *workloadSpec
+		current  string
+		claims   []corev1.PersistentVolumeClaim
+	}{
+		"deployment to stateful set": {statefulSet, workloadKindDeployment, nil},
+		"stateful set to deployment": {deployment, workloadKindStatefulSet, []corev1.PersistentVolumeClaim{claim("data", "10Gi")}},
+		"resized claim":              {statefulSet, workloadKindStatefulSet, []corev1.PersistentVolumeClaim{claim("data", "5Gi")}},
+		"added claim":                {statefulSet, workloadKindStatefulSet, []corev1.PersistentVolumeClaim{claim("logs", "10Gi")}},
+		"removed claim":              {statefulSet, workloadKindStatefulSet, []corev1.PersistentVolumeClaim{claim("data", "10Gi"), claim("logs", "1Gi")}},
+	}
+	for name, c := range cases {
+		if err := c.workload.validateTransition(c.current, c.claims); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import "fmt"
+
+// define the runtime worker template data - a long running background
+// process, e.g., a queue consumer, without a kubernetes service,
+// ingress or health check
+type NopeusWorkerMicroservice struct {
+	*NopeusDefaultMicroservice
+}
+
+// return the worker template data
+func NewWorkerTemplateData(cfg *NopeusConfig, name string, service *Service, env string) (ServiceTemplateData, error) {
+	if err := validateWorker(service); err != nil {
+		return &NopeusWorkerMicroservice{}, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	microservice, err := newMicroservice(cfg, name, service, env)
+	if err != nil {
+		return &NopeusWorkerMicroservice{}, err
+	}
+
+	if err := setReplicas(microservice, service); err != nil {
+		return &NopeusWorkerMicroservice{}, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	// the default chart renders a deployment without a kubernetes service for the workload kind of the values
+	microservice.ValuesTemplate = "worker.values.yaml"
+
+	// workers render no ports unless they are declared
+	if err := service.GetProbes().ValidatePorts(service.Ports); err != nil {
+		return &NopeusWorkerMicroservice{}, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	// workers have no health check url to default to
+	if service.GetProbes() != nil {
+		microservice.Values.Custom["Probes"] = service.GetProbes()
+	}
+
+	return &NopeusWorkerMicroservice{NopeusDefaultMicroservice: microservice}, nil
+}
+
+// validate the configs of a worker
+// workers don't receive traffic so anything routing to them is rejected
+func validateWorker(service *Service) error {
+	if service.Ingress != nil {
+		return fmt.Errorf("a %s can't have an ingress", ServiceKindWorker)
+	}
+	if service.HealthCheckURL != "" {
+		return fmt.Errorf("a %s can't have a health_url - use probes instead", ServiceKindWorker)
+	}
+	if service.GetStrategy().IsProgressive() {
+		return fmt.Errorf("a %s can't use the %s strategy - progressive strategies require an ingress", ServiceKindWorker, service.GetStrategy().GetType())
+	}
+	if service.Schedule != "" {
+		return fmt.Errorf("a %s can't have a schedule - use the %s kind instead", ServiceKindWorker, ServiceKindCronJob)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+// TestValidateWorker rejects anything routing traffic to a worker
+func TestValidateWorker(t *testing.T) {
+	cases := map[string]*Service{
+		"ingress":  {Ingress: &Ingress{}},
+		"health":   {HealthCheckURL: "/status"},
+		"canary":   {Strategy: &Strategy{Type: StrategyCanary}},
+		"schedule": {Schedule: "@daily"},
+	}
+	for name, service := range cases {
+		if err := validateWorker(service); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if err := validateWorker(&Service{Replicas: 3}); err != nil {
+		t.Errorf("expected a valid worker, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/helm"
+	sgck "github.com/salfatigroup/nopeus/kubernetes"
+)
+
+// the time to wait for the rotation job and the restarted services
+const rotateCredentialsTimeout = 10 * time.Minute
+
+// rotate the credentials of the database in the cluster of the kube context
+// and restart the services reading them. defaults to the active kube context
+func RotateDatabaseCredentials(ctx context.Context, cfg *config.NopeusConfig, dbName string, kubeContext string) ([]sgck.Workload, error) {
+	var db *config.DatabaseStorage
+	if cfg.CAL.GetStorage() != nil {
+		for _, candidate := range cfg.CAL.GetStorage().Database {
+			if candidate.Name == dbName {
+				db = candidate
+			}
+		}
+	}
+	if db == nil {
+		return nil, fmt.Errorf("the database %s is not defined in the nopeus config", dbName)
+	}
+
+	if kubeContext == "" {
+		var err error
+		if kubeContext, err = getActiveKubeContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := sgck.NewClientset(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.RotateDatabaseCredentials(ctx, client, cfg.Runtime.DefaultNamespace, db, rotateCredentialsTimeout, func(line string) {
+		helm.Logf("[%s] %s", db.Name, line)
+	})
+}
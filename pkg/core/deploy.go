@@ -1,10 +1,9 @@
 package core
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 
 	"github.com/salfatigroup/gologsnag"
@@ -14,16 +13,20 @@ import (
 	"github.com/salfatigroup/nopeus/logger"
 	"github.com/salfatigroup/nopeus/plugins"
 	"github.com/salfatigroup/nopeus/remote"
+	"github.com/salfatigroup/nopeus/tracing"
 )
 
 // Deploy the application to the cloud based on
 // the provided configurations
-func Deploy(cfg *config.NopeusConfig) error {
+func Deploy(ctx context.Context, cfg *config.NopeusConfig) error {
 	// run plugins on init
-	if err := plugins.RunOnInit(cfg); err != nil {
+	if err := plugins.RunOnInit(ctx, cfg); err != nil {
 		return err
 	}
 
+	// file references in the env values are relative to the nopeus config
+	config.RegisterSecretResolver("file", &config.FileResolver{Dir: filepath.Dir(cfg.Runtime.ConfigPath)})
+
 	// in parallel deploy all the environments
 	for envName, envData := range cfg.CAL.GetEnvironments() {
 		// load the environment variables for this deployment
@@ -42,7 +45,7 @@ func Deploy(cfg *config.NopeusConfig) error {
 		// the default kubectl to connect with the k8s cluster
 		// and the kubectl is not thread safe
 		// Ideally, I should use the k8s client library to connect.
-		if err := deployEnvironment(envName, envData, cfg); err != nil {
+		if err := deployEnvironment(ctx, envName, envData, cfg); err != nil {
 			return err
 		}
 
@@ -51,7 +54,7 @@ func Deploy(cfg *config.NopeusConfig) error {
 	}
 
 	// run plugins on finish
-	if err := plugins.RunOnFinish(cfg); err != nil {
+	if err := plugins.RunOnFinish(ctx, cfg); err != nil {
 		return err
 	}
 
@@ -59,26 +62,23 @@ func Deploy(cfg *config.NopeusConfig) error {
 }
 
 // parse the environment variables per service for this environment
+// the terraform outputs are referenced once terraform has run
 func parseServiceVariables(cfg *config.NopeusConfig, envName string) error {
-	// parse environment variables for every service per environment
-	services, err := cfg.CAL.GetServices()
-	if err != nil {
-		return err
-	}
-
-	for _, service := range services {
-		if err := service.ParseEnvironmentVariables(envName); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return cfg.CAL.ParseEnvironmentVariables(envName, cfg.Runtime.DefaultNamespace, nil)
 }
 
 // deploy a single environment to the cloud
-func deployEnvironment(envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
+func deployEnvironment(ctx context.Context, envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) (err error) {
+	ctx, span := tracing.Start(ctx, "environment "+envName, tracing.Environment(envName))
+	defer func() { tracing.End(span, err) }()
+
 	// notify the user
-	fmt.Println(util.GrayText("Launching ") + util.GrayText(envName) + util.GrayText(" environment to the cloud"))
+	util.Emit(&util.Event{
+		Type:        util.EventPhaseStart,
+		Phase:       "environment",
+		Environment: envName,
+		Message:     "Launching " + envName + " environment to the cloud",
+	})
 
 	// generate files
 	// in parallel, generate the terraform files and the k8s/helm charts and manifests
@@ -87,8 +87,11 @@ func deployEnvironment(envName string, envData *config.EnvironmentConfig, cfg *c
 	var err1 error
 	var err2 error
 
+	// each environment renders and applies its own services
+	cfg.Runtime.HelmRuntime = &config.HelmRuntime{}
+
 	// plugins run before generate
-	if err := plugins.RunBeforeGenerate(cfg, envName, envData); err != nil {
+	if err := plugins.RunBeforeGenerate(ctx, cfg, envName, envData); err != nil {
 		return err
 	}
 
@@ -100,9 +103,9 @@ func deployEnvironment(envName string, envData *config.EnvironmentConfig, cfg *c
 		defer wg.Done()
 		logger.Debug("Generating terraform files")
 		logger.Publish(&gologsnag.PublishOptions{Event: "generating-terraform-files", Tags: &gologsnag.Tags{"environment": envName}})
-		if err1 = generateTerraformFiles(envName, envData, cfg); err1 != nil {
-			return
-		}
+		_, span := tracing.Start(ctx, "generate terraform", tracing.Environment(envName))
+		err1 = generateTerraformFiles(envName, envData, cfg)
+		tracing.End(span, err1)
 	}()
 
 	// generate the k8s/helm charts and manifests
@@ -111,9 +114,9 @@ func deployEnvironment(envName string, envData *config.EnvironmentConfig, cfg *c
 		defer wg.Done()
 		logger.Debug("Generating k8s/helm charts and manifests")
 		logger.Publish(&gologsnag.PublishOptions{Event: "generating-k8s-helm-charts-and-manifests", Tags: &gologsnag.Tags{"environment": envName}})
-		if err2 = generateK8sHelmCharts(envName, envData, cfg); err2 != nil {
-			return
-		}
+		_, span := tracing.Start(ctx, "generate helm", tracing.Environment(envName))
+		err2 = generateK8sHelmCharts(envName, envData, cfg)
+		tracing.End(span, err2)
 	}()
 	// wait for all the goroutines to finish
 	wg.Wait()
@@ -126,33 +129,37 @@ func deployEnvironment(envName string, envData *config.EnvironmentConfig, cfg *c
 	}
 
 	// plugins run after generate
-	if err := plugins.RunAfterGenerate(cfg, envName, envData); err != nil {
+	if err := plugins.RunAfterGenerate(ctx, cfg, envName, envData); err != nil {
+		return err
+	}
+
+	// show the rollout order next to the terraform plan, before anything is applied
+	if err := emitRolloutPlan(envName, cfg); err != nil {
 		return err
 	}
 
 	// get remote cache from nopeus cloud
-	if err := getRemoteCache(envName, envData, cfg); err != nil {
+	if err := getRemoteCache(ctx, envName, envData, cfg); err != nil {
 		return err
 	}
 
 	// unfold nopeus.state files
-	_, err := unfoldNopeusState(envName, envData, cfg)
-	if err != nil {
+	if _, err := unfoldNopeusState(envName, envData, cfg); err != nil {
 		return err
 	}
 
 	// plugins run before deploy
-	if err := plugins.RunBeforeDeploy(cfg, envName, envData); err != nil {
+	if err := plugins.RunBeforeDeploy(ctx, cfg, envName, envData); err != nil {
 		return err
 	}
 
 	// deploy the application to the cloud
-	if err := deployToCloud(envName, envData, cfg); err != nil {
+	if err := deployToCloud(ctx, envName, envData, cfg); err != nil {
 		return err
 	}
 
 	// plugins run after deploy
-	if err := plugins.RunAfterDeploy(cfg, envName, envData); err != nil {
+	if err := plugins.RunAfterDeploy(ctx, cfg, envName, envData); err != nil {
 		return err
 	}
 
@@ -163,10 +170,11 @@ func deployEnvironment(envName string, envData *config.EnvironmentConfig, cfg *c
 	}
 
 	// remote caching to nopeus cloud
-	if err := setRemoteCache(cfg, newstate); err != nil {
+	if err := setRemoteCache(ctx, cfg, newstate); err != nil {
 		return err
 	}
 
+	util.Emit(&util.Event{Type: util.EventPhaseEnd, Phase: "environment", Environment: envName})
 	return nil
 }
 
@@ -251,33 +259,42 @@ func unfoldNopeusState(envName string, envData *config.EnvironmentConfig, cfg *c
 // deployToCloud deploys the application to the cloud
 // based on the provided configurations, terraform files,
 // k8s/helm charts and manifests
-func deployToCloud(envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
+func deployToCloud(ctx context.Context, envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
 	// deploy the terraform files
 	logger.Debug("Deploying terraform files")
 	logger.Publish(&gologsnag.PublishOptions{Event: "deploy-terraform-files", Tags: &gologsnag.Tags{"environment": envName}})
-	if err := runTerraform(envName, envData, cfg); err != nil {
+	if err := runTerraform(ctx, envName, envData, cfg); err != nil {
 		return err
 	}
 
-	fmt.Println(
-		"🚀",
-		util.GradientText("[NOPEUS::MAX-Q::"+strings.ToUpper(envName)+"]", "#db2777", "#f9a8d4"),
-		"- applying the cloud configurations",
-	)
+	// expose the user supplied terraform outputs to the services
+	if err := exposeTerraformOutputs(envName, envData, cfg); err != nil {
+		return err
+	}
+
+	util.Emit(&util.Event{
+		Type:        util.EventPhaseStart,
+		Phase:       "max-q",
+		Environment: envName,
+		Message:     "applying the cloud configurations",
+	})
 
 	// deploy the k8s/helm charts and manifests
 	logger.Debug("Deploying k8s/helm charts and manifests")
 	logger.Publish(&gologsnag.PublishOptions{Event: "deploy-k8s-helm-charts", Tags: &gologsnag.Tags{"environment": envName}})
-	if err := runK8s(envName, envData, cfg); err != nil {
+	if err := runK8s(ctx, envName, envData, cfg); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func setRemoteCache(cfg *config.NopeusConfig, state *cache.NopeusState) error {
+func setRemoteCache(ctx context.Context, cfg *config.NopeusConfig, state *cache.NopeusState) (err error) {
 	// create remote session if token is provided
 	if cfg.Runtime.NopeusCloudToken != "" {
+		_, span := tracing.Start(ctx, "set remote cache", tracing.Environment(state.EnvironmentName))
+		defer func() { tracing.End(span, err) }()
+
 		logger.Debug("Setting remote cache")
 		logger.Publish(&gologsnag.PublishOptions{Event: "set-remote-cache", Tags: &gologsnag.Tags{"environment": state.EnvironmentName}})
 		session, err := remote.NewRemoteSession(cfg.Runtime.NopeusCloudToken)
@@ -294,9 +311,12 @@ func setRemoteCache(cfg *config.NopeusConfig, state *cache.NopeusState) error {
 	return nil
 }
 
-func getRemoteCache(envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
+func getRemoteCache(ctx context.Context, envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) (err error) {
 	// create remote session if token is provided
 	if cfg.Runtime.NopeusCloudToken != "" {
+		_, span := tracing.Start(ctx, "get remote cache", tracing.Environment(envName))
+		defer func() { tracing.End(span, err) }()
+
 		logger.Debug("Getting remote cache")
 		logger.Publish(&gologsnag.PublishOptions{Event: "get-remote-cache", Tags: &gologsnag.Tags{"environment": envName}})
 		session, err := remote.NewRemoteSession(cfg.Runtime.NopeusCloudToken)
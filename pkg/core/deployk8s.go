@@ -8,11 +8,15 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/salfatigroup/nopeus/cli/util"
 	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/helm"
 	sgck "github.com/salfatigroup/nopeus/kubernetes"
 	"github.com/salfatigroup/nopeus/logger"
+	"github.com/salfatigroup/nopeus/plugins"
+	"github.com/salfatigroup/nopeus/tracing"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -30,7 +34,7 @@ func newKubernetesClient() (*kubernetes.Clientset, error) {
 }
 
 // run and deploy k8s and helm files per environment
-func runK8s(envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
+func runK8s(ctx context.Context, envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
 	// apply the helm charts for each environment
 	var kubeContext string
 
@@ -56,7 +60,20 @@ func runK8s(envName string, envData *config.EnvironmentConfig, cfg *config.Nopeu
 		}
 	}
 
-	if err := applyK8sHelmCharts(cfg, envName, envData, kubeContext); err != nil {
+	// stream the helm logs to the progress renderer
+	progress := util.NewProgress("helm releases")
+	helm.SetDebugLog(progress.Logf)
+	defer func() {
+		helm.SetDebugLog(nil)
+		progress.Done()
+	}()
+
+	if err := applyK8sHelmCharts(ctx, cfg, envName, envData, kubeContext); err != nil {
+		// keep the services that became healthy before the failure
+		// from being redeployed on the next liftoff
+		if storeErr := plugins.StoreChecksums(cfg, envName, envData); storeErr != nil {
+			logger.Debugf("failed to store the checksums of environment %s: %v", envName, storeErr)
+		}
 		return err
 	}
 
@@ -121,34 +138,108 @@ func createPrivateRegistrySecrets(cfg *config.NopeusConfig, envName string, envD
 	return nil
 }
 
-func applyK8sHelmCharts(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig, kubeContext string) error {
-	// apply the helm charts for the environment
+// show the order the helm charts of the environment are applied in
+// invalid dependencies are reported before anything is applied
+func emitRolloutPlan(envName string, cfg *config.NopeusConfig) error {
+	batches, err := config.SortByDependencies(cfg.Runtime.HelmRuntime.ServiceTemplateData)
+	if err != nil {
+		return err
+	}
+
+	util.Emit(&util.Event{Type: util.EventRolloutPlan, Environment: envName, Batches: config.GetBatchNames(batches)})
+	return nil
+}
+
+// apply the helm charts of the environment in the order of their dependencies
+// the independent services of each batch are applied in parallel
+func applyK8sHelmCharts(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig, kubeContext string) error {
+	batches, err := config.SortByDependencies(cfg.Runtime.HelmRuntime.ServiceTemplateData)
+	if err != nil {
+		return err
+	}
+
+	// the services and hooks exposed to a database read its password even
+	// when they don't depend on it, so the credentials are created first
 	for _, service := range cfg.Runtime.HelmRuntime.ServiceTemplateData {
-		// compare service checksum to the checksum map
-		// and skip if the same
-		serviceChecksum, err := service.GetChecksum()
-		if err != nil {
-			return err
+		if db, ok := service.(*config.NopeusStorageMicroservice); ok {
+			if err := db.EnsureCredentials(kubeContext); err != nil {
+				return err
+			}
 		}
+	}
 
-		logger.Debugf("serviceChecksum: %s", serviceChecksum)
-		logger.Debugf("service values: %+v", service)
-		logger.Debugf("service helm values: %+v", service.GetHelmValues())
-
-		logger.Debugf("envData checksum: %s", envData.GetChecksum(service.GetName()))
-		if serviceChecksum == envData.GetChecksum(service.GetName()) {
-			fmt.Println(util.GrayText("Skipping service " + service.GetName() + " because it is up to date"))
-			continue
+	for _, batch := range batches {
+		var wg sync.WaitGroup
+		errs := make([]error, len(batch))
+		limit := make(chan struct{}, cfg.CAL.GetConcurrency())
+		for i, service := range batch {
+			wg.Add(1)
+			go func(i int, service config.ServiceTemplateData) {
+				defer wg.Done()
+				limit <- struct{}{}
+				defer func() { <-limit }()
+
+				errs[i] = applyK8sHelmChart(ctx, cfg, envName, envData, service, kubeContext)
+			}(i, service)
 		}
+		wg.Wait()
 
-		if err := service.ApplyHelmChart(kubeContext); err != nil {
-			return err
+		// the next batches depend on this one
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// apply a single helm chart unless it's up to date
+func applyK8sHelmChart(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig, service config.ServiceTemplateData, kubeContext string) error {
+	// the checksum and the values of the api gateway include the traffic
+	// split the progressive rollouts change while it is applied
+	if service.GetName() == gatewayServiceName {
+		gatewayMutex.Lock()
+		defer gatewayMutex.Unlock()
+	}
+
+	// compare service checksum to the checksum map
+	// and skip if the same
+	serviceChecksum, err := service.GetChecksum()
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("serviceChecksum: %s", serviceChecksum)
+	logger.Debugf("service values: %+v", service)
+	logger.Debugf("service helm values: %+v", service.GetHelmValues())
+
+	logger.Debugf("envData checksum: %s", envData.GetChecksum(service.GetName()))
+	if serviceChecksum == envData.GetChecksum(service.GetName()) {
+		util.Emit(&util.Event{
+			Type:        util.EventServiceSkipped,
+			Environment: envName,
+			Service:     service.GetName(),
+			Message:     "Skipping service " + service.GetName() + " because it is up to date",
+		})
+		return nil
+	}
+
+	_, span := tracing.Start(ctx, "helm release "+service.GetName(), tracing.Environment(envName), tracing.Service(service.GetName()))
+	err = applyServiceHelmChart(ctx, cfg, envName, service, kubeContext)
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+
+	// only services that reached a healthy state are marked as deployed
+	envData.SetChecksum(service.GetName(), serviceChecksum)
+	util.Emit(&util.Event{Type: util.EventServiceApplied, Environment: envName, Service: service.GetName()})
+
+	return nil
+}
+
 // connect to relevant k8s cluster
 func connectToCluster(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) (string, error) {
 	cloudVendor, err := cfg.CAL.GetCloudVendor()
@@ -4,14 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/terraform-exec/tfexec"
 	"github.com/salfatigroup/nopeus/cli/util"
 	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/logger"
+	"github.com/salfatigroup/nopeus/templates"
+	"github.com/salfatigroup/nopeus/tracing"
 )
 
 // run and deploy terraform files per environment
-func runTerraform(envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
+func runTerraform(ctx context.Context, envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
 	cloudVendor, err := cfg.CAL.GetCloudVendor()
 	if err != nil {
 		return err
@@ -19,7 +26,7 @@ func runTerraform(envName string, envData *config.EnvironmentConfig, cfg *config
 
 	workingTfDir := fmt.Sprintf("%s/%s/%s", cfg.Runtime.TmpFileLocation, cloudVendor, envName)
 
-	if err := runTerraformFile(cfg, envName, envData, workingTfDir); err != nil {
+	if err := runTerraformFile(ctx, cfg, envName, envData, workingTfDir); err != nil {
 		return err
 	}
 
@@ -27,44 +34,70 @@ func runTerraform(envName string, envData *config.EnvironmentConfig, cfg *config
 }
 
 // run and deploy terraform file
-func runTerraformFile(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig, workingTfDir string) error {
-	tf, err := tfexec.NewTerraform(workingTfDir, cfg.Runtime.TerraformExecutablePath)
+func runTerraformFile(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig, workingTfDir string) error {
+	execPath, err := cfg.GetTerraformExecutablePath()
+	if err != nil {
+		return err
+	}
+
+	tf, err := tfexec.NewTerraform(workingTfDir, execPath)
 	if err != nil {
 		return err
 	}
 
 	// initialize terraform
-	fmt.Println(util.GrayText("Initializing your cloud deployment..."))
-	if err := tf.Init(context.Background(), tfexec.Upgrade(true)); err != nil {
+	util.Message("Initializing your cloud deployment...")
+	initOptions, err := getTerraformInitOptions(cfg, envName)
+	if err != nil {
+		return err
+	}
+	if err := runWithProgress(ctx, tf, envName, "terraform init", func(ctx context.Context) error {
+		return tf.Init(ctx, initOptions...)
+	}); err != nil {
 		return err
 	}
 
 	// plan the terraform file and output the plan file
-	fmt.Println(util.GrayText("Planning your cloud infrastructure..."))
-	newChanges, err := tf.Plan(context.Background())
-	if err != nil {
+	util.Message("Planning your cloud infrastructure...")
+	planFile := filepath.Join(workingTfDir, "nopeus.tfplan")
+	var newChanges bool
+	if err := runWithProgress(ctx, tf, envName, "terraform plan", func(ctx context.Context) (err error) {
+		newChanges, err = tf.Plan(ctx, tfexec.Out(planFile))
+		return err
+	}); err != nil {
 		return err
 	}
+	defer os.Remove(planFile)
 
 	// apply the plan in dry run mode file if new changes are found
 	if newChanges {
-		fmt.Println(util.GrayText("Upading your cloud infrastructure... This can take a while, going to grab some coffee ☕️..."))
+		// report the planned changes
+		changes, err := getTerraformChanges(ctx, tf, planFile)
+		if err != nil {
+			return err
+		}
+		util.Emit(&util.Event{Type: util.EventTerraformChanges, Environment: envName, Changes: changes})
+
 		if cfg.Runtime.DryRun {
-			fmt.Println(util.GrayText("Dry run mode enabled, no changes will be applied to the cloud"))
+			util.Message("Dry run mode enabled, no changes will be applied to the cloud")
 		} else {
-			if err := tf.Apply(context.Background()); err != nil {
+			util.Message("Updating your cloud infrastructure... This can take a while")
+			if err := runWithProgress(ctx, tf, envName, "terraform apply", func(ctx context.Context) error {
+				return tf.Apply(ctx, tfexec.DirOrPlan(planFile))
+			}); err != nil {
 				return err
 			}
 
-			fmt.Println(util.GrayText("Your cloud infrastructure has been updated."))
+			util.Message("Your cloud infrastructure has been updated.")
 		}
 	} else {
-		fmt.Println(util.GrayText("No new changes found in terraform plan"), "🤷")
+		util.Emit(&util.Event{Type: util.EventTerraformChanges, Environment: envName, Changes: &util.TerraformChanges{}})
+		util.Message("No new changes found in terraform plan 🤷")
 	}
 
 	// get the terraform output and set them to the infrastructure config
-	fmt.Println(util.GrayText("Getting the cloud infrastructure output..."))
-	if outputs, err := tf.Output(context.Background()); err != nil {
+	util.Message("Getting the cloud infrastructure output...")
+	if outputs, err := tf.Output(ctx); err != nil {
 		return err
 	} else {
 		// apply the outputs to the infrastructure configs
@@ -75,7 +108,7 @@ func runTerraformFile(cfg *config.NopeusConfig, envName string, envData *config.
 			}
 			envData.SetOutputs(outputs)
 		} else if cfg.Runtime.DryRun {
-			fmt.Println(util.GrayText("Dry run mode enabled, ignoring environment output"))
+			util.Message("Dry run mode enabled, ignoring environment output")
 		} else {
 			return fmt.Errorf("environment variable is missing from terraform outputs - %s not found", string(envBytes.Value))
 		}
@@ -83,3 +116,129 @@ func runTerraformFile(cfg *config.NopeusConfig, envName string, envData *config.
 
 	return nil
 }
+
+// count the resource changes in the given terraform plan file
+func getTerraformChanges(ctx context.Context, tf *tfexec.Terraform, planFile string) (*util.TerraformChanges, error) {
+	plan, err := tf.ShowPlanFile(ctx, planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &util.TerraformChanges{}
+	for _, resource := range plan.ResourceChanges {
+		if resource.Change == nil {
+			continue
+		}
+
+		actions := resource.Change.Actions
+		switch {
+		case actions.Replace():
+			changes.Add++
+			changes.Destroy++
+		case actions.Create():
+			changes.Add++
+		case actions.Update():
+			changes.Change++
+		case actions.Delete():
+			changes.Destroy++
+		}
+	}
+
+	return changes, nil
+}
+
+// the terraform runner streaming the command output, replaced in the tests
+type outputStreamer interface {
+	SetStdout(w io.Writer)
+	SetStderr(w io.Writer)
+}
+
+// run a terraform command in its own span while
+// streaming its output to the progress renderer
+func runWithProgress(ctx context.Context, tf outputStreamer, envName string, step string, run func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, step, tracing.Environment(envName))
+	progress := util.NewProgress(step)
+	tf.SetStdout(progress)
+	tf.SetStderr(progress)
+
+	err := run(ctx)
+	tracing.End(span, err)
+
+	// stop streaming before running other commands e.g., terraform output
+	tf.SetStdout(nil)
+	tf.SetStderr(nil)
+	progress.Done()
+
+	return err
+}
+
+// return the terraform init options including the
+// backend configs when a remote backend is configured
+func getTerraformInitOptions(cfg *config.NopeusConfig, envName string) ([]tfexec.InitOption, error) {
+	initOptions := []tfexec.InitOption{tfexec.Upgrade(true)}
+
+	backend := cfg.CAL.GetTerraform().GetBackend()
+	if backend == nil {
+		return initOptions, nil
+	}
+
+	backendConfig, err := backend.GetBackendConfig(cfg.CAL.GetName(), envName)
+	if err != nil {
+		return nil, err
+	}
+
+	// reconfigure to ignore any local state left from previous runs
+	initOptions = append(initOptions, tfexec.Reconfigure(true))
+	for _, value := range backendConfig {
+		initOptions = append(initOptions, tfexec.BackendConfig(value))
+	}
+
+	return initOptions, nil
+}
+
+// resolve the ${outputs.*} references of the services and expose the outputs
+// of the user supplied terraform files to every service as environment
+// variables e.g., output "ses_domain" becomes SES_DOMAIN.
+// variables that were explicitly defined by the service are not overridden
+func exposeTerraformOutputs(envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) error {
+	values := envData.GetOutputValues()
+	if values == nil {
+		return nil
+	}
+
+	// the parsed variables are updated in place for the rendered services
+	if err := cfg.CAL.ParseEnvironmentVariables(envName, cfg.Runtime.DefaultNamespace, values); err != nil {
+		return err
+	}
+
+	services, err := cfg.CAL.GetServices()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range envData.GetExtraOutputs() {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		for serviceName, service := range services {
+			if service.DefinesVariable(key) {
+				logger.Debugf("service %s already defines %s, skipping terraform output", serviceName, key)
+				continue
+			}
+
+			service.AddEnvironmentVariable(key, value, envName)
+		}
+	}
+
+	// render the helm values again to include the new variables
+	for _, serviceTemplateData := range cfg.Runtime.HelmRuntime.ServiceTemplateData {
+		if err := templates.RenderHelmTemplateFile(serviceTemplateData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
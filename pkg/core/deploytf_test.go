@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/salfatigroup/nopeus/config"
+)
+
+// TestGetTerraformInitOptions passes the backend configs to terraform init
+func TestGetTerraformInitOptions(t *testing.T) {
+	for name, test := range map[string]struct {
+		terraform *config.TerraformConfig
+		expected  []tfexec.InitOption
+		fails     bool
+	}{
+		"nopeus state": {
+			terraform: nil,
+			expected:  []tfexec.InitOption{tfexec.Upgrade(true)},
+		},
+		"s3 backend": {
+			terraform: &config.TerraformConfig{Backend: &config.TerraformBackend{
+				Type:   "s3",
+				Config: map[string]string{"bucket": "state", "key": "{{ .Name }}/{{ .Environment }}.tfstate"},
+			}},
+			expected: []tfexec.InitOption{
+				tfexec.Upgrade(true),
+				tfexec.Reconfigure(true),
+				tfexec.BackendConfig("bucket=state"),
+				tfexec.BackendConfig("key=shop/prod.tfstate"),
+			},
+		},
+		"invalid backend config": {
+			terraform: &config.TerraformConfig{Backend: &config.TerraformBackend{Type: "s3", Config: map[string]string{"key": "{{ .Name"}}},
+			fails:     true,
+		},
+	} {
+		cfg := &config.NopeusConfig{CAL: &config.CloudApplicationLayerConfig{Name: "shop", Terraform: test.terraform}}
+
+		options, err := getTerraformInitOptions(cfg, "prod")
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(options, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", name, test.expected, options)
+		}
+	}
+}
+
+// TestExposeTerraformOutputs injects the extra outputs without overriding the service variables
+func TestExposeTerraformOutputs(t *testing.T) {
+	cfg := &config.NopeusConfig{
+		Runtime: config.NewRuntimeConfig(),
+		CAL: &config.CloudApplicationLayerConfig{
+			Name: "shop",
+			Services: map[string]*config.Service{
+				"api": {EnvironmentVariables: config.EnvironmentVariables{
+					"SES_DOMAIN": "mail.example.com",
+					"CLUSTER":    "${outputs.name}",
+				}},
+				"mailer": {Kind: config.ServiceKindWorker},
+			},
+		},
+	}
+	if err := parseServiceVariables(cfg, "prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	envData := &config.EnvironmentConfig{}
+	envData.SetOutputs(map[string]tfexec.OutputMeta{
+		"name":       {Value: json.RawMessage(`"nopeus-shop-prod"`)},
+		"ses_domain": {Value: json.RawMessage(`"example.com"`)},
+		"queue-url":  {Value: json.RawMessage(`"https://sqs/jobs"`)},
+		"ports":      {Value: json.RawMessage(`[80,443]`)},
+	})
+	envData.SetExtraOutputs([]string{"ses_domain", "queue-url", "ports", "missing"})
+
+	if err := exposeTerraformOutputs("prod", envData, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]map[string]string{
+		"api": {
+			"SES_DOMAIN": "mail.example.com",
+			"QUEUE_URL":  "https://sqs/jobs",
+			"PORTS":      "[80,443]",
+			"CLUSTER":    "nopeus-shop-prod",
+		},
+		"mailer": {
+			"SES_DOMAIN": "example.com",
+			"QUEUE_URL":  "https://sqs/jobs",
+			"PORTS":      "[80,443]",
+		},
+	} {
+		variables := cfg.CAL.Services[name].GetEnvironmentVariables("prod")
+		for key, value := range expected {
+			if variables[key] != value {
+				t.Errorf("service %s: expected %s=%s, got %q", name, key, value, variables[key])
+			}
+		}
+		if _, ok := variables["MISSING"]; ok {
+			t.Errorf("service %s: expected outputs without a value to be skipped", name)
+		}
+		if _, ok := variables["NAME"]; ok {
+			t.Errorf("service %s: expected only the outputs of the extra dirs to be exposed", name)
+		}
+	}
+}
+
+// TestExposeTerraformOutputsBeforeTerraform keeps the variables until terraform has run
+func TestExposeTerraformOutputsBeforeTerraform(t *testing.T) {
+	cfg := &config.NopeusConfig{
+		Runtime: config.NewRuntimeConfig(),
+		CAL: &config.CloudApplicationLayerConfig{
+			Services: map[string]*config.Service{"api": {}},
+		},
+	}
+	envData := &config.EnvironmentConfig{}
+	envData.SetExtraOutputs([]string{"ses_domain"})
+
+	if err := exposeTerraformOutputs("prod", envData, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.CAL.Services["api"].GetEnvironmentVariables("prod")) != 0 {
+		t.Errorf("expected no variables without terraform outputs")
+	}
+}
+
+// a terraform runner recording the writers of the command output
+type fakeStreamer struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (s *fakeStreamer) SetStdout(w io.Writer) { s.stdout = w }
+
+func (s *fakeStreamer) SetStderr(w io.Writer) { s.stderr = w }
+
+// TestRunWithProgress streams the command output while it runs
+func TestRunWithProgress(t *testing.T) {
+	tf := &fakeStreamer{}
+	runErr := fmt.Errorf("terraform apply failed")
+
+	err := runWithProgress(context.Background(), tf, "staging", "terraform apply", func(ctx context.Context) error {
+		if tf.stdout == nil || tf.stderr == nil {
+			t.Errorf("expected the output to be streamed while the command runs")
+		} else if _, err := tf.stdout.Write([]byte("aws_s3_bucket.main: Creating...\n")); err != nil {
+			t.Errorf("expected the progress to consume the output, got %v", err)
+		}
+		return runErr
+	})
+
+	if err != runErr {
+		t.Errorf("expected the command error, got %v", err)
+	}
+	if tf.stdout != nil || tf.stderr != nil {
+		t.Errorf("expected the output to be detached once the command is done")
+	}
+}
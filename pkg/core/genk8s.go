@@ -1,9 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/salfatigroup/nopeus/config"
 	"github.com/salfatigroup/nopeus/logger"
@@ -102,22 +102,16 @@ func updateHelmRuntime(cfg *config.NopeusConfig, envName string, envData *config
 			// to the root CAL config
 			service.Ingress.ServiceName = serviceName
 			service.Ingress.Namespace = cfg.Runtime.DefaultNamespace
-			if service.GetEnvironmentVariables(envName)["PORT"] != "" {
-				port, err := strconv.Atoi(service.EnvironmentVariables["PORT"])
-				if err != nil {
-					return err
-				}
 
-				service.Ingress.Port = port
+			// route each path to the service port it targets
+			ports, err := service.GetPorts(envName)
+			if err != nil {
+				return fmt.Errorf("service %s: %w", serviceName, err)
 			}
-			ingressList = append(ingressList, service.Ingress)
-		}
-
-		// add the DATABASE_URL to each service for each storage
-		if cfg.CAL.GetStorage() != nil {
-			for _, db := range cfg.CAL.GetStorage().Database {
-				service.AddEnvironmentVariable("STORAGE_DATABASE_URL", db.Name, envName)
+			if err := service.Ingress.ResolvePorts(ports); err != nil {
+				return fmt.Errorf("service %s: %w", serviceName, err)
 			}
+			ingressList = append(ingressList, service.Ingress)
 		}
 	}
 
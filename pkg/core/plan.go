@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"sort"
+
+	"github.com/salfatigroup/nopeus/cli/util"
+	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/plugins"
+)
+
+// Plan shows the order the services of each environment are applied
+// in without connecting to the cloud or rendering any file
+func Plan(ctx context.Context, cfg *config.NopeusConfig) error {
+	environments := cfg.CAL.GetEnvironments()
+	envNames := make([]string, 0, len(environments))
+	for envName := range environments {
+		envNames = append(envNames, envName)
+	}
+	sort.Strings(envNames)
+
+	for _, envName := range envNames {
+		batches, err := planEnvironment(ctx, cfg, envName, environments[envName])
+		if err != nil {
+			return err
+		}
+
+		util.Emit(&util.Event{Type: util.EventRolloutPlan, Environment: envName, Batches: batches})
+	}
+
+	return nil
+}
+
+// return the batches of services the environment is applied in,
+// the same batches a liftoff of the environment applies
+func planEnvironment(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) ([][]string, error) {
+	cfg.Runtime.HelmRuntime = &config.HelmRuntime{}
+	if err := plugins.RunBeforeGenerate(ctx, cfg, envName, envData); err != nil {
+		return nil, err
+	}
+	if err := updateHelmRuntime(cfg, envName, envData); err != nil {
+		return nil, err
+	}
+
+	batches, err := config.SortByDependencies(cfg.Runtime.HelmRuntime.ServiceTemplateData)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.GetBatchNames(batches), nil
+}
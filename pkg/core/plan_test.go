@@ -0,0 +1,55 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/salfatigroup/nopeus/config"
+)
+
+// create the config of an application with a database and two services
+func newPlanConfig(t *testing.T, services map[string]*config.Service) *config.NopeusConfig {
+	cfg := config.NewNopeusConfig()
+	cfg.Runtime.ConfigPath = t.TempDir() + "/nopeus.yaml"
+	cfg.Runtime.TmpFileLocation = t.TempDir()
+	cfg.CAL = &config.CloudApplicationLayerConfig{
+		CloudVendor: "aws",
+		Services:    services,
+		Storage:     &config.Storage{Database: []*config.DatabaseStorage{{Name: "main", Type: "postgres", Version: "14"}}},
+	}
+	return cfg
+}
+
+// TestPlanEnvironment returns the batches the environment is applied in
+func TestPlanEnvironment(t *testing.T) {
+	cfg := newPlanConfig(t, map[string]*config.Service{
+		"api":    {Image: "shop/api", DependsOn: []string{"main"}},
+		"worker": {Image: "shop/worker", Kind: config.ServiceKindWorker, DependsOn: []string{"api"}},
+	})
+
+	batches, err := planEnvironment(context.Background(), cfg, "staging", config.NewEnvironmentConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the infrastructure of the registered plugins is applied first
+	expected := [][]string{{"main"}, {"api"}, {"worker"}}
+	if len(batches) < len(expected) || !reflect.DeepEqual(batches[len(batches)-len(expected):], expected) {
+		t.Errorf("expected the batches %v, got %v", expected, batches)
+	}
+}
+
+// TestPlanEnvironmentCycle reports the dependency cycles without applying anything
+func TestPlanEnvironmentCycle(t *testing.T) {
+	cfg := newPlanConfig(t, map[string]*config.Service{
+		"api": {Image: "shop/api", DependsOn: []string{"web"}},
+		"web": {Image: "shop/web", DependsOn: []string{"api"}},
+	})
+
+	_, err := planEnvironment(context.Background(), cfg, "staging", config.NewEnvironmentConfig())
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+}
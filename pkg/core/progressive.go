@@ -0,0 +1,272 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/salfatigroup/nopeus/cli/util"
+	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/helm"
+	sgck "github.com/salfatigroup/nopeus/kubernetes"
+	"github.com/salfatigroup/nopeus/logger"
+	"github.com/salfatigroup/nopeus/templates"
+)
+
+// how often the candidate release is checked while a step is paused
+const progressiveCheckInterval = 10 * time.Second
+
+// the name of the api gateway release routing the ingress traffic
+const gatewayServiceName = "api-gateway"
+
+// the services are applied in parallel but share a single api gateway
+// every render and apply of the gateway goes through this lock
+var gatewayMutex sync.Mutex
+
+// the kinds embedding the default microservice expose its rollout strategy
+type strategyRelease interface {
+	GetStrategy() *config.Strategy
+}
+
+// the release operations a progressive rollout runs on the
+// service and its candidate, replaced in the tests
+type rolloutRelease interface {
+	GetName() string
+	PrepareRelease(kubeContext string) error
+	RunHook(kubeContext string, name string) error
+	ApplyHelmChart(kubeContext string) error
+	ApplyRelease(kubeContext string) error
+	DeleteHelmChart(kubeContext string) error
+}
+
+// define a progressive rollout of a service that was released before
+type progressiveRollout struct {
+	envName     string
+	kubeContext string
+	strategy    *config.Strategy
+	ingress     *config.Ingress
+
+	// the current release of the service, upgraded once the candidate is promoted
+	service rolloutRelease
+
+	// the new version running next to the current one during the rollout
+	candidate rolloutRelease
+
+	// the api gateway splitting the traffic between the two
+	gateway config.ServiceTemplateData
+
+	// verify the candidate stays healthy for the pause duration
+	verify func(ctx context.Context, pause time.Duration, threshold float64) error
+}
+
+// apply the service helm chart using the service rollout strategy
+func applyServiceHelmChart(ctx context.Context, cfg *config.NopeusConfig, envName string, service config.ServiceTemplateData, kubeContext string) error {
+	// render the gateway again to keep the traffic split of the running
+	// rollouts, the caller holds the gateway lock
+	if service.GetName() == gatewayServiceName {
+		return applyGateway(service, kubeContext)
+	}
+
+	// only services run next to their candidate behind the api gateway
+	if release, ok := service.(strategyRelease); ok && release.GetStrategy().IsProgressive() {
+		microservice, ok := service.(*config.NopeusDefaultMicroservice)
+		if !ok {
+			return fmt.Errorf("service %s: the %s strategy is only supported by the %s kind", service.GetName(), release.GetStrategy().GetType(), config.ServiceKindService)
+		}
+
+		return applyProgressiveRollout(ctx, cfg, envName, microservice, kubeContext)
+	}
+
+	return service.ApplyHelmChart(kubeContext)
+}
+
+// roll out the new version next to the current one and shift the traffic
+// to it through the api gateway step by step. the first release of the
+// service has no traffic to shift and is applied as is
+func applyProgressiveRollout(ctx context.Context, cfg *config.NopeusConfig, envName string, service *config.NopeusDefaultMicroservice, kubeContext string) error {
+	helmClient, err := helm.NewHelmClient(service.Namespace, kubeContext)
+	if err != nil {
+		return err
+	}
+	if release, _ := helmClient.GetChartByName(service.GetName()); cfg.Runtime.DryRun || release == nil {
+		return service.ApplyHelmChart(kubeContext)
+	}
+
+	gateway := getServiceTemplateData(cfg, gatewayServiceName)
+	if gateway == nil || service.GetIngress() == nil {
+		return fmt.Errorf("the %s strategy of service %s requires an ingress", service.GetStrategy().GetType(), service.GetName())
+	}
+
+	candidate := service.GetCandidate()
+	if err := templates.RenderHelmTemplateFile(candidate); err != nil {
+		return err
+	}
+
+	rollout := &progressiveRollout{
+		envName:     envName,
+		kubeContext: kubeContext,
+		strategy:    service.GetStrategy(),
+		ingress:     service.GetIngress(),
+		service:     service,
+		candidate:   candidate,
+		gateway:     gateway,
+		verify: func(ctx context.Context, pause time.Duration, threshold float64) error {
+			return verifyCandidate(ctx, candidate, kubeContext, pause, threshold)
+		},
+	}
+	return rollout.run(ctx)
+}
+
+// deploy the candidate and shift the traffic to it step by step. the
+// traffic is restored and the candidate removed as soon as one of the
+// checks fails, otherwise the service is promoted to the new version
+func (r *progressiveRollout) run(ctx context.Context) error {
+	steps, err := r.strategy.GetSteps()
+	if err != nil {
+		return err
+	}
+
+	name := r.service.GetName()
+	rollback := func(err error) error {
+		if weightErr := setCanaryWeight(r.gateway, r.ingress, "", 0, r.kubeContext); weightErr != nil {
+			logger.Errorf("failed to restore the traffic of service %s: %v", name, weightErr)
+		}
+		if deleteErr := r.candidate.DeleteHelmChart(r.kubeContext); deleteErr != nil {
+			logger.Errorf("failed to remove the new version of service %s: %v", name, deleteErr)
+		}
+
+		return fmt.Errorf("%w\nthe %s rollout of service %s was rolled back", err, r.strategy.GetType(), name)
+	}
+
+	// the pre-deploy hook runs before the new version gets any traffic
+	// and reads the files and secrets of the new version
+	if err := r.service.PrepareRelease(r.kubeContext); err != nil {
+		return err
+	}
+	if err := r.service.RunHook(r.kubeContext, config.HookPreDeploy); err != nil {
+		return err
+	}
+
+	// deploy the new version next to the current one
+	util.Emit(&util.Event{
+		Type:        util.EventMessage,
+		Environment: r.envName,
+		Service:     name,
+		Message:     fmt.Sprintf("Rolling out service %s with the %s strategy", name, r.strategy.GetType()),
+	})
+	if err := r.candidate.ApplyHelmChart(r.kubeContext); err != nil {
+		return rollback(err)
+	}
+
+	// shift the traffic and verify the new version after each step
+	for _, step := range steps {
+		util.Emit(&util.Event{
+			Type:        util.EventMessage,
+			Environment: r.envName,
+			Service:     name,
+			Message:     fmt.Sprintf("Sending %d%% of the traffic to the new version of service %s", step.Weight, name),
+		})
+		if err := setCanaryWeight(r.gateway, r.ingress, r.candidate.GetName(), step.Weight, r.kubeContext); err != nil {
+			return rollback(err)
+		}
+
+		if err := r.verify(ctx, step.Pause, r.strategy.GetErrorThreshold()); err != nil {
+			return rollback(err)
+		}
+	}
+
+	// promote the new version and send the traffic back to the service
+	if err := r.service.ApplyRelease(r.kubeContext); err != nil {
+		return rollback(err)
+	}
+	if err := setCanaryWeight(r.gateway, r.ingress, "", 0, r.kubeContext); err != nil {
+		return err
+	}
+	if err := r.candidate.DeleteHelmChart(r.kubeContext); err != nil {
+		return err
+	}
+
+	return r.service.RunHook(r.kubeContext, config.HookPostDeploy)
+}
+
+// route the given share of the ingress traffic to the candidate
+// release and apply the api gateway. an empty candidate routes
+// all the traffic back to the service
+func setCanaryWeight(gateway config.ServiceTemplateData, ingress *config.Ingress, candidate string, weight int, kubeContext string) error {
+	gatewayMutex.Lock()
+	defer gatewayMutex.Unlock()
+
+	ingress.CanaryServiceName = candidate
+	ingress.CanaryWeight = weight
+
+	return applyGateway(gateway, kubeContext)
+}
+
+// render and apply the api gateway with the current traffic split of the
+// ingresses. the caller must hold the gateway lock
+func applyGateway(gateway config.ServiceTemplateData, kubeContext string) error {
+	if err := templates.RenderHelmTemplateFile(gateway); err != nil {
+		return err
+	}
+
+	return gateway.ApplyHelmChart(kubeContext)
+}
+
+// verify the candidate release stays healthy for the pause duration
+// fails when its pods crash or its error rate exceeds the threshold
+func verifyCandidate(ctx context.Context, candidate *config.NopeusDefaultMicroservice, kubeContext string, pause time.Duration, threshold float64) error {
+	helmClient, err := helm.NewHelmClient(candidate.Namespace, kubeContext)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := sgck.NewClientset(kubeContext)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(pause)
+	for {
+		release, err := helmClient.GetChartByName(candidate.GetName())
+		if err != nil {
+			return err
+		}
+		if err := helm.WaitForRollout(release, kubeContext, candidate.GetRolloutTimeout()); err != nil {
+			return err
+		}
+
+		// the error rate is read from the prometheus plugin
+		// and is skipped if prometheus can't be reached
+		rate, ok, err := sgck.ErrorRate(ctx, kubeClient, candidate.GetName())
+		if err != nil {
+			logger.Debugf("failed to read the error rate of %s: %v", candidate.GetName(), err)
+		} else if ok && rate > threshold {
+			return fmt.Errorf("the error rate of %s is %.2f%%, above the %.2f%% threshold", candidate.GetName(), rate*100, threshold*100)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+		if remaining > progressiveCheckInterval {
+			remaining = progressiveCheckInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(remaining):
+		}
+	}
+}
+
+// return the service template data with the given name, nil if not found
+func getServiceTemplateData(cfg *config.NopeusConfig, name string) config.ServiceTemplateData {
+	for _, service := range cfg.Runtime.HelmRuntime.ServiceTemplateData {
+		if service.GetName() == name {
+			return service
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/salfatigroup/nopeus/config"
+)
+
+// a release recording the operations of the rollout
+type fakeRelease struct {
+	name string
+	ops  *[]string
+}
+
+func (r *fakeRelease) record(op string) error {
+	*r.ops = append(*r.ops, fmt.Sprintf("%s %s", r.name, op))
+	return nil
+}
+
+func (r *fakeRelease) GetName() string { return r.name }
+
+func (r *fakeRelease) PrepareRelease(kubeContext string) error { return r.record("prepare") }
+
+func (r *fakeRelease) RunHook(kubeContext string, name string) error { return r.record(name) }
+
+func (r *fakeRelease) ApplyHelmChart(kubeContext string) error { return r.record("apply") }
+
+func (r *fakeRelease) ApplyRelease(kubeContext string) error { return r.record("release") }
+
+func (r *fakeRelease) DeleteHelmChart(kubeContext string) error { return r.record("delete") }
+
+// the canary weight rendered in the gateway values
+var canaryWeightPattern = regexp.MustCompile(`canary:\s+upstream: (\S+)\s+weight: (\d+)`)
+
+// an api gateway recording the traffic split of each apply
+type fakeGateway struct {
+	*config.NopeusDefaultMicroservice
+	ops *[]string
+}
+
+func (g *fakeGateway) ApplyHelmChart(kubeContext string) error {
+	values, err := os.ReadFile(g.ValuesPath)
+	if err != nil {
+		return err
+	}
+
+	split := "gateway 0"
+	if match := canaryWeightPattern.FindStringSubmatch(string(values)); match != nil {
+		split = fmt.Sprintf("gateway %s %s", match[1], match[2])
+	}
+	*g.ops = append(*g.ops, split)
+	return nil
+}
+
+// create a rollout of the api service in two canary steps
+func newTestRollout(t *testing.T, verify func(ctx context.Context, pause time.Duration, threshold float64) error) (*progressiveRollout, *[]string) {
+	ops := &[]string{}
+	ingress := &config.Ingress{
+		ServiceName: "api",
+		Namespace:   "nopeus-app",
+		Paths:       []config.IngressPath{{Path: "/api", TargetPort: 8080, Protocol: "http"}},
+	}
+	gateway := &fakeGateway{
+		NopeusDefaultMicroservice: &config.NopeusDefaultMicroservice{
+			Name:           gatewayServiceName,
+			ValuesTemplate: "proxy.values.yaml",
+			ValuesPath:     filepath.Join(t.TempDir(), "api-gateway.values.yaml"),
+			Values: &config.HelmRendererValues{
+				Name: gatewayServiceName,
+				Custom: map[string]interface{}{
+					"Ingress":     []*config.Ingress{ingress},
+					"HostPrefix":  "",
+					"StreamPorts": []int{},
+				},
+			},
+		},
+		ops: ops,
+	}
+
+	return &progressiveRollout{
+		envName: "staging",
+		strategy: &config.Strategy{
+			Type: config.StrategyCanary,
+			Steps: []*config.CanaryStep{
+				{Weight: 10, Pause: "1m"},
+				{Weight: 50, Pause: "1m"},
+			},
+		},
+		ingress:   ingress,
+		service:   &fakeRelease{name: "api", ops: ops},
+		candidate: &fakeRelease{name: "api-canary", ops: ops},
+		gateway:   gateway,
+		verify:    verify,
+	}, ops
+}
+
+// TestProgressiveRolloutPromotion shifts the traffic step by step and promotes the candidate
+func TestProgressiveRolloutPromotion(t *testing.T) {
+	pauses := []time.Duration{}
+	rollout, ops := newTestRollout(t, func(ctx context.Context, pause time.Duration, threshold float64) error {
+		pauses = append(pauses, pause)
+		return nil
+	})
+
+	if err := rollout.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"api prepare",
+		"api " + config.HookPreDeploy,
+		"api-canary apply",
+		"gateway api-canary 10",
+		"gateway api-canary 50",
+		"api release",
+		"gateway 0",
+		"api-canary delete",
+		"api " + config.HookPostDeploy,
+	}
+	if !reflect.DeepEqual(*ops, expected) {
+		t.Errorf("expected the operations %v, got %v", expected, *ops)
+	}
+	if !reflect.DeepEqual(pauses, []time.Duration{time.Minute, time.Minute}) {
+		t.Errorf("expected the candidate to be verified after each step, got %v", pauses)
+	}
+	if rollout.ingress.CanaryServiceName != "" || rollout.ingress.CanaryWeight != 0 {
+		t.Errorf("expected the traffic to be routed back to the service, got %+v", rollout.ingress)
+	}
+}
+
+// TestProgressiveRolloutAbort restores the traffic and removes the candidate when a check fails
+func TestProgressiveRolloutAbort(t *testing.T) {
+	rollout, ops := newTestRollout(t, func(ctx context.Context, pause time.Duration, threshold float64) error {
+		return fmt.Errorf("the error rate of api-canary is above the threshold")
+	})
+
+	err := rollout.run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected the rollout to be rolled back, got %v", err)
+	}
+
+	expected := []string{
+		"api prepare",
+		"api " + config.HookPreDeploy,
+		"api-canary apply",
+		"gateway api-canary 10",
+		"gateway 0",
+		"api-canary delete",
+	}
+	if !reflect.DeepEqual(*ops, expected) {
+		t.Errorf("expected the operations %v, got %v", expected, *ops)
+	}
+}
+
+// a service kind exposing a strategy without supporting candidate releases
+type fakeStrategyService struct {
+	*config.NopeusWorkerMicroservice
+	strategy *config.Strategy
+}
+
+func (s *fakeStrategyService) GetStrategy() *config.Strategy { return s.strategy }
+
+// TestApplyServiceHelmChartUnsupportedStrategy rejects progressive strategies of other kinds
+func TestApplyServiceHelmChartUnsupportedStrategy(t *testing.T) {
+	service := &fakeStrategyService{
+		NopeusWorkerMicroservice: &config.NopeusWorkerMicroservice{
+			NopeusDefaultMicroservice: &config.NopeusDefaultMicroservice{Name: "consumer"},
+		},
+		strategy: &config.Strategy{Type: config.StrategyBlueGreen},
+	}
+
+	err := applyServiceHelmChart(context.Background(), &config.NopeusConfig{}, "staging", service, "")
+	if err == nil || !strings.Contains(err.Error(), "only supported by the service kind") {
+		t.Errorf("expected the strategy to be rejected, got %v", err)
+	}
+}
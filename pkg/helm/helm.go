@@ -11,6 +11,22 @@ import (
 	helmrepo "helm.sh/helm/v3/pkg/repo"
 )
 
+// an additional sink for the helm debug logs - e.g., the progress renderer
+var debugLog func(format string, v ...interface{})
+
+// stream the helm debug logs to the given function, nil to disable
+func SetDebugLog(fn func(format string, v ...interface{})) {
+	debugLog = fn
+}
+
+// log a helm message to the debug logs and the additional sink
+func Logf(format string, v ...interface{}) {
+	logger.Debugf(format, v...)
+	if debugLog != nil {
+		debugLog(format, v...)
+	}
+}
+
 // define the helm client for nopeus
 type HelmClient struct {
 	Client helmclient.Client
@@ -35,7 +51,7 @@ func NewHelmClient(namespace, context string) (*HelmClient, error) {
 				// Change this to your own logger. Default is 'log.Printf(format, v...)'.
 				// fmt.Printf(format, v...)
 				// fmt.Printf("\n")
-				logger.Debugf(format, v...)
+				Logf(format, v...)
 			},
 		},
 		KubeContext: context,
@@ -70,6 +86,16 @@ func (h *HelmClient) InstallChart(releaseName, chartName, namespace, values stri
 	return err
 }
 
+// roll the release back to its previous revision. the first
+// revision of a release has nothing to roll back to and is uninstalled
+func (h *HelmClient) RollbackRelease(rel *release.Release, spec *helmclient.ChartSpec) error {
+	if rel.Version <= 1 {
+		return h.Client.UninstallRelease(spec)
+	}
+
+	return h.Client.RollbackRelease(spec)
+}
+
 // delete a helm chart
 func (h *HelmClient) UninstallChart(releaseName string) error {
 	return h.Client.UninstallReleaseByName(releaseName)
@@ -0,0 +1,48 @@
+package helm
+
+import (
+	"reflect"
+	"testing"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// a helm client recording the release operations
+type fakeClient struct {
+	helmclient.Client
+	ops []string
+}
+
+func (c *fakeClient) RollbackRelease(spec *helmclient.ChartSpec) error {
+	c.ops = append(c.ops, "rollback "+spec.ReleaseName)
+	return nil
+}
+
+func (c *fakeClient) UninstallRelease(spec *helmclient.ChartSpec) error {
+	c.ops = append(c.ops, "uninstall "+spec.ReleaseName)
+	return nil
+}
+
+// TestRollbackRelease uninstalls the first revision and rolls back the later ones
+func TestRollbackRelease(t *testing.T) {
+	for name, test := range map[string]struct {
+		version  int
+		expected []string
+	}{
+		"first revision":  {version: 1, expected: []string{"uninstall api"}},
+		"second revision": {version: 2, expected: []string{"rollback api"}},
+		"later revision":  {version: 7, expected: []string{"rollback api"}},
+	} {
+		client := &fakeClient{}
+		helmClient := &HelmClient{Client: client}
+
+		err := helmClient.RollbackRelease(&release.Release{Name: "api", Version: test.version}, &helmclient.ChartSpec{ReleaseName: "api"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(client.ops, test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, client.ops)
+		}
+	}
+}
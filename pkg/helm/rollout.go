@@ -0,0 +1,34 @@
+package helm
+
+import (
+	"context"
+	"time"
+
+	"github.com/salfatigroup/nopeus/kubernetes"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// wait until the deployments, statefulsets and jobs of the release are ready
+// fails as soon as a pod is crashing or its image can't be pulled
+func WaitForRollout(rel *release.Release, kubeContext string, timeout time.Duration) error {
+	workloads, err := kubernetes.ParseWorkloads(rel.Manifest, rel.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	client, err := kubernetes.NewClientset(kubeContext)
+	if err != nil {
+		return err
+	}
+
+	watcher := kubernetes.NewRolloutWatcher(client)
+	watcher.OnProgress = func(workload kubernetes.Workload, status string) {
+		Logf("waiting for %s of release %s: %s", workload, rel.Name, status)
+	}
+
+	return watcher.Wait(context.Background(), workloads, timeout)
+}
@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"os"
 
+	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 )
@@ -46,3 +47,22 @@ func LoadKubeconfig() (*api.Config, error) {
 	// load the kubeconfig
 	return clientcmd.LoadFromFile(kubeconfigPath)
 }
+
+// create a kubernetes client for the given kube context
+func NewClientset(kubeContext string) (*k8s.Clientset, error) {
+	// get the kubeconfig path
+	kubeconfigPath, err := FindKubeconfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return k8s.NewForConfig(restConfig)
+}
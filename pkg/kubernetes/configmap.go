@@ -0,0 +1,51 @@
+package kubernetes
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// create the configmap or replace the data of the existing one
+func ApplyConfigMap(ctx context.Context, client k8s.Interface, configMap *corev1.ConfigMap) error {
+	configMaps := client.CoreV1().ConfigMaps(configMap.Namespace)
+
+	existing, err := configMaps.Get(ctx, configMap.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.Labels = configMap.Labels
+	existing.Data = configMap.Data
+	_, err = configMaps.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// delete the configmaps with the given labels except the one to keep
+func PruneConfigMaps(ctx context.Context, client k8s.Interface, namespace string, selector map[string]string, keep string) error {
+	configMaps := client.CoreV1().ConfigMaps(namespace)
+
+	list, err := configMaps.List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return err
+	}
+
+	for _, configMap := range list.Items {
+		if configMap.Name == keep {
+			continue
+		}
+		if err := configMaps.Delete(ctx, configMap.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
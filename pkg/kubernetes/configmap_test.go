@@ -0,0 +1,63 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newConfigMap returns a configmap of the api service files
+func newConfigMap(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app", Labels: map[string]string{"service": "api"}},
+		Data:       data,
+	}
+}
+
+// TestApplyConfigMap creates the configmap and updates it on the next apply
+func TestApplyConfigMap(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	if err := ApplyConfigMap(ctx, client, newConfigMap("api-files", map[string]string{"a": "1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyConfigMap(ctx, client, newConfigMap("api-files", map[string]string{"a": "2"})); err != nil {
+		t.Fatal(err)
+	}
+
+	configMap, err := client.CoreV1().ConfigMaps("app").Get(ctx, "api-files", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configMap.Data["a"] != "2" {
+		t.Errorf("expected the data to be updated, got %v", configMap.Data)
+	}
+}
+
+// TestPruneConfigMaps removes the previous configmaps of the service only
+func TestPruneConfigMaps(t *testing.T) {
+	ctx := context.Background()
+	other := newConfigMap("web-files-1", nil)
+	other.Labels = map[string]string{"service": "web"}
+	client := fake.NewSimpleClientset(newConfigMap("api-files-1", nil), newConfigMap("api-files-2", nil), other)
+
+	if err := PruneConfigMaps(ctx, client, "app", map[string]string{"service": "api"}, "api-files-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := client.CoreV1().ConfigMaps("app").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, configMap := range list.Items {
+		names[configMap.Name] = true
+	}
+	if names["api-files-1"] || !names["api-files-2"] || !names["web-files-1"] {
+		t.Errorf("expected only the previous api configmap to be removed, got %v", names)
+	}
+}
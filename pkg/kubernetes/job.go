@@ -0,0 +1,183 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// how long the logs are drained once the job is done
+const jobLogsGracePeriod = 5 * time.Second
+
+// the label the job controller sets on the pods of a job to its uid
+const jobControllerUIDLabel = "controller-uid"
+
+// define a one-off job, e.g., a database migration
+type JobSpec struct {
+	Name            string
+	Namespace       string
+	Image           string
+	Command         []string
+	Env             map[string]string
+	ImagePullSecret string
+	Labels          map[string]string
+
+	// the variables read from the keys of secrets by their name
+	SecretEnv map[string]*SecretKeyRef
+}
+
+// define the key of a secret a variable is read from
+type SecretKeyRef struct {
+	Name string
+	Key  string
+}
+
+// create the kubernetes job of the spec. the job is not retried
+// so a failing command fails the job right away
+func NewJob(spec *JobSpec) *batchv1.Job {
+	backoffLimit := int32(0)
+
+	// sort the variables for a stable job definition. the secret variables
+	// come first since kubernetes only expands the variables defined before
+	secretEnv := []corev1.EnvVar{}
+	for name, ref := range spec.SecretEnv {
+		secretEnv = append(secretEnv, corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name},
+				Key:                  ref.Key,
+			},
+		}})
+	}
+	sort.Slice(secretEnv, func(i, j int) bool { return secretEnv[i].Name < secretEnv[j].Name })
+	env := []corev1.EnvVar{}
+	for name, value := range spec.Env {
+		env = append(env, corev1.EnvVar{Name: name, Value: value})
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+	env = append(secretEnv, env...)
+
+	podSpec := corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers: []corev1.Container{{
+			Name:    spec.Name,
+			Image:   spec.Image,
+			Command: spec.Command,
+			Env:     env,
+		}},
+	}
+	if spec.ImagePullSecret != "" {
+		podSpec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: spec.ImagePullSecret}}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      spec.Name,
+			Namespace: spec.Namespace,
+			Labels:    spec.Labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: spec.Labels},
+				Spec:       podSpec,
+			},
+		},
+	}
+}
+
+// run the job to completion while streaming the logs of its pod
+// a previous run of the job with the same name is replaced
+func (w *RolloutWatcher) RunJob(ctx context.Context, job *batchv1.Job, timeout time.Duration, onLog func(line string)) error {
+	jobs := w.Client.BatchV1().Jobs(job.Namespace)
+
+	// remove the previous run and wait for it to be gone
+	propagation := metav1.DeletePropagationBackground
+	if err := jobs.Delete(ctx, job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err := wait.PollImmediateWithContext(ctx, w.Interval, timeout, func(ctx context.Context) (bool, error) {
+		_, err := jobs.Get(ctx, job.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		return err
+	}
+
+	created, err := jobs.Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	// stream the logs while waiting for the job
+	logsCtx, cancelLogs := context.WithCancel(ctx)
+	defer cancelLogs()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.streamJobLogs(logsCtx, created, onLog)
+	}()
+
+	err = w.Wait(ctx, []Workload{{Kind: "Job", Name: created.Name, Namespace: created.Namespace}}, timeout)
+
+	// let the log stream drain before returning
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(jobLogsGracePeriod):
+		cancelLogs()
+		<-done
+	}
+
+	return err
+}
+
+// follow the logs of the job pod once it started
+func (w *RolloutWatcher) streamJobLogs(ctx context.Context, job *batchv1.Job, onLog func(line string)) {
+	pods := w.Client.CoreV1().Pods(job.Namespace)
+
+	// wait for the pod to start. the pods are selected by the uid of the job
+	// since the pods of the previous run may still be terminating
+	var pod *corev1.Pod
+	selector := jobControllerUIDLabel + "=" + string(job.UID)
+	if err := wait.PollImmediateUntilWithContext(ctx, w.Interval, func(ctx context.Context) (bool, error) {
+		list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return false, nil
+		}
+		for i := range list.Items {
+			if list.Items[i].Status.Phase != corev1.PodPending && list.Items[i].Status.Phase != "" {
+				pod = &list.Items[i]
+				return true, nil
+			}
+		}
+		return false, nil
+	}); err != nil {
+		return
+	}
+
+	stream, err := pods.GetLogs(pod.Name, &corev1.PodLogOptions{Follow: true}).Stream(ctx)
+	if err != nil {
+		return
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		onLog(scanner.Text())
+	}
+}
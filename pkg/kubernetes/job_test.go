@@ -0,0 +1,155 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// finish the job once it is created, like the job controller would
+func finishJob(t *testing.T, watcher *RolloutWatcher, name string, condition batchv1.JobConditionType) {
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		job, err := watcher.Client.BatchV1().Jobs("app").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-1", Namespace: "app", Labels: map[string]string{"job-name": name, jobControllerUIDLabel: string(job.UID)}},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		}
+		if _, err := watcher.Client.CoreV1().Pods("app").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			t.Error(err)
+		}
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue, Message: "done"}}
+		if _, err := watcher.Client.BatchV1().Jobs("app").UpdateStatus(ctx, job, metav1.UpdateOptions{}); err != nil {
+			t.Error(err)
+		}
+		return
+	}
+
+	t.Error("the job was never created")
+}
+
+// create a watcher that assigns a uid to the created jobs, like the api server would
+func newJobWatcher(objects ...runtime.Object) *RolloutWatcher {
+	watcher := newWatcher(objects...)
+	runs := 0
+	watcher.Client.(*fake.Clientset).PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		runs++
+		job.UID = types.UID(fmt.Sprintf("%s-run-%d", job.Name, runs))
+		return false, nil, nil
+	})
+
+	return watcher
+}
+
+// TestRunJob streams the logs of a completed job
+func TestRunJob(t *testing.T) {
+	watcher := newJobWatcher()
+	job := NewJob(&JobSpec{Name: "api-pre-deploy", Namespace: "app", Image: "api:1.0.0", Command: []string{"migrate"}})
+
+	go finishJob(t, watcher, job.Name, batchv1.JobComplete)
+
+	var mu sync.Mutex
+	logs := []string{}
+	err := watcher.RunJob(context.Background(), job, time.Second, func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, line)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logs) == 0 {
+		t.Errorf("expected the job logs to be streamed")
+	}
+}
+
+// TestRunJobFailed returns an error when the job fails
+func TestRunJobFailed(t *testing.T) {
+	watcher := newJobWatcher()
+	job := NewJob(&JobSpec{Name: "api-post-deploy", Namespace: "app", Image: "api:1.0.0", Command: []string{"smoke-test"}})
+
+	go finishJob(t, watcher, job.Name, batchv1.JobFailed)
+
+	err := watcher.RunJob(context.Background(), job, time.Second, func(string) {})
+	var rolloutErr *RolloutError
+	if !errors.As(err, &rolloutErr) {
+		t.Fatalf("expected a failed job error, got %v", err)
+	}
+}
+
+// TestNewJob sorts the environment after the secret variables and disables the retries
+func TestNewJob(t *testing.T) {
+	job := NewJob(&JobSpec{
+		Name:            "api-pre-deploy",
+		Image:           "api:1.0.0",
+		Env:             map[string]string{"B": "2", "A": "1"},
+		ImagePullSecret: "dockerconfig",
+		SecretEnv:       map[string]*SecretKeyRef{"AA": {Name: "api-secrets", Key: "AA"}},
+	})
+
+	container := job.Spec.Template.Spec.Containers[0]
+	if container.Env[0].Name != "AA" || container.Env[1].Name != "A" || container.Env[2].Name != "B" {
+		t.Errorf("expected the secret variables before the sorted environment variables, got %v", container.Env)
+	}
+	if ref := container.Env[0].ValueFrom.SecretKeyRef; ref.Name != "api-secrets" || ref.Key != "AA" {
+		t.Errorf("expected the secret variable to reference the secret, got %v", ref)
+	}
+	if *job.Spec.BackoffLimit != 0 || job.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("expected the job not to be retried")
+	}
+	if job.Spec.Template.Spec.ImagePullSecrets[0].Name != "dockerconfig" {
+		t.Errorf("expected the image pull secret")
+	}
+}
+
+// TestRunJobLogsOfNewRun ignores the pods of the previous run that are still terminating
+func TestRunJobLogsOfNewRun(t *testing.T) {
+	previous := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-pre-deploy-0", Namespace: "app", Labels: map[string]string{"job-name": "api-pre-deploy", jobControllerUIDLabel: "previous"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	watcher := newJobWatcher(previous)
+	job := NewJob(&JobSpec{Name: "api-pre-deploy", Namespace: "app", Image: "api:1.0.0", Command: []string{"migrate"}})
+
+	go finishJob(t, watcher, job.Name, batchv1.JobComplete)
+	if err := watcher.RunJob(context.Background(), job, time.Second, func(string) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := false
+	for _, action := range watcher.Client.(*fake.Clientset).Actions() {
+		list, ok := action.(k8stesting.ListAction)
+		if !ok || action.GetResource().Resource != "pods" {
+			continue
+		}
+
+		listed = true
+		if selector := list.GetListRestrictions().Labels.String(); selector != jobControllerUIDLabel+"=api-pre-deploy-run-1" {
+			t.Errorf("expected the pods to be selected by the job uid, got %s", selector)
+		}
+	}
+	if !listed {
+		t.Errorf("expected the job pods to be listed")
+	}
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// the prometheus installed by the nopeus prometheus plugin
+const (
+	prometheusNamespace = "nopeus"
+	prometheusService   = "prometheus-kube-prometheus-prometheus"
+	prometheusPort      = "9090"
+)
+
+// define the prometheus instant query response
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// query a single value from the in-cluster prometheus through the api server
+// returns false if the query has no value, e.g., when there is no traffic yet
+func QueryPrometheus(ctx context.Context, client k8s.Interface, query string) (float64, bool, error) {
+	raw, err := client.CoreV1().Services(prometheusNamespace).
+		ProxyGet("http", prometheusService, prometheusPort, "/api/v1/query", map[string]string{"query": query}).
+		DoRaw(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var response prometheusResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return 0, false, err
+	}
+	if response.Status != "success" {
+		return 0, false, fmt.Errorf("prometheus query failed: %s", response.Error)
+	}
+	if len(response.Data.Result) == 0 || len(response.Data.Result[0].Value) != 2 {
+		return 0, false, nil
+	}
+
+	// the value is a [timestamp, "value"] pair
+	text, ok := response.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected prometheus value %v", response.Data.Result[0].Value[1])
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false, nil
+	}
+
+	return value, true, nil
+}
+
+// the requests counter of the prometheus plugin of the api gateway. the
+// gateway chart names the kong service of each upstream, including the
+// candidate upstream of a progressive rollout, after the release it targets
+const (
+	gatewayRequestsMetric = "kong_http_requests_total"
+	gatewayServiceLabel   = "service"
+)
+
+// return the ratio of the 5xx responses of the given upstream
+// served by the api gateway over the last minute
+func ErrorRate(ctx context.Context, client k8s.Interface, upstream string) (float64, bool, error) {
+	selector := fmt.Sprintf("%s=%q", gatewayServiceLabel, upstream)
+	query := fmt.Sprintf(
+		`sum(rate(%[1]s{%[2]s,code=~"5.."}[1m])) / sum(rate(%[1]s{%[2]s}[1m]))`,
+		gatewayRequestsMetric,
+		selector,
+	)
+
+	return QueryPrometheus(ctx, client, query)
+}
@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// a canned response of the api server service proxy
+type proxyResponse []byte
+
+func (r proxyResponse) DoRaw(context.Context) ([]byte, error) {
+	return r, nil
+}
+
+func (r proxyResponse) Stream(context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(r)), nil
+}
+
+// create a client whose prometheus responds with the given body
+func newPrometheusClient(body string) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependProxyReactor("services", func(action k8stesting.Action) (bool, restclient.ResponseWrapper, error) {
+		return true, proxyResponse(body), nil
+	})
+	return client
+}
+
+// TestErrorRate reads the error rate from the prometheus response
+func TestErrorRate(t *testing.T) {
+	client := newPrometheusClient(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"0.25"]}]}}`)
+	rate, ok, err := ErrorRate(context.Background(), client, "api-canary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || rate != 0.25 {
+		t.Errorf("expected an error rate of 0.25, got %v (%v)", rate, ok)
+	}
+}
+
+// TestErrorRateQuery selects the exact upstream, not the ones containing its name
+func TestErrorRateQuery(t *testing.T) {
+	var query string
+	client := fake.NewSimpleClientset()
+	client.PrependProxyReactor("services", func(action k8stesting.Action) (bool, restclient.ResponseWrapper, error) {
+		query = action.(k8stesting.ProxyGetAction).GetParams()["query"]
+		return true, proxyResponse(`{"status":"success","data":{"resultType":"vector","result":[]}}`), nil
+	})
+
+	if _, _, err := ErrorRate(context.Background(), client, "api-canary"); err != nil {
+		t.Fatal(err)
+	}
+	expected := `sum(rate(kong_http_requests_total{service="api-canary",code=~"5.."}[1m])) / sum(rate(kong_http_requests_total{service="api-canary"}[1m]))`
+	if query != expected {
+		t.Errorf("expected the query %s, got %s", expected, query)
+	}
+}
+
+// TestErrorRateNoTraffic reports no value without traffic
+func TestErrorRateNoTraffic(t *testing.T) {
+	for _, body := range []string{
+		`{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"NaN"]}]}}`,
+	} {
+		_, ok, err := ErrorRate(context.Background(), newPrometheusClient(body), "api-canary")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Errorf("expected no error rate for %s", body)
+		}
+	}
+}
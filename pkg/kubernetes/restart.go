@@ -0,0 +1,77 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// the pod template annotation kubectl rollout restart sets
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// restart the deployments and stateful sets whose pods read the secret,
+// e.g., to pick up rotated credentials. returns the restarted workloads
+func RestartWorkloadsUsingSecret(ctx context.Context, client k8s.Interface, namespace string, secret string) ([]Workload, error) {
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`, restartedAtAnnotation, time.Now().UTC().Format(time.RFC3339)))
+	restarted := []Workload{}
+
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments.Items {
+		if !usesSecret(&deployment.Spec.Template.Spec, secret) {
+			continue
+		}
+		if _, err := client.AppsV1().Deployments(namespace).Patch(ctx, deployment.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return nil, err
+		}
+		restarted = append(restarted, Workload{Kind: "Deployment", Name: deployment.Name, Namespace: namespace})
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if !usesSecret(&statefulSet.Spec.Template.Spec, secret) {
+			continue
+		}
+		if _, err := client.AppsV1().StatefulSets(namespace).Patch(ctx, statefulSet.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return nil, err
+		}
+		restarted = append(restarted, Workload{Kind: "StatefulSet", Name: statefulSet.Name, Namespace: namespace})
+	}
+
+	return restarted, nil
+}
+
+// return true if any container of the pod reads the secret
+func usesSecret(pod *corev1.PodSpec, secret string) bool {
+	containers := append(append([]corev1.Container{}, pod.InitContainers...), pod.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == secret {
+				return true
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == secret {
+				return true
+			}
+		}
+	}
+
+	for _, volume := range pod.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == secret {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newPodTemplate returns a pod template reading the variable from the secret
+func newPodTemplate(secret string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+		Name: "app",
+		Env: []corev1.EnvVar{{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret}, Key: "password"},
+		}}},
+	}}}}
+}
+
+// TestRestartWorkloadsUsingSecret restarts only the workloads reading the secret
+func TestRestartWorkloadsUsingSecret(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "app"}, Spec: appsv1.DeploymentSpec{Template: newPodTemplate("database-secrets-main")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"}, Spec: appsv1.DeploymentSpec{Template: newPodTemplate("web-secrets")}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "app"}, Spec: appsv1.StatefulSetSpec{Template: newPodTemplate("database-secrets-main")}},
+	)
+
+	restarted, err := RestartWorkloadsUsingSecret(ctx, client, "app", "database-secrets-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted) != 2 || restarted[0].String() != "deployment/api" || restarted[1].String() != "statefulset/worker" {
+		t.Errorf("expected the api and the worker to restart, got %v", restarted)
+	}
+
+	api, err := client.AppsV1().Deployments("app").Get(ctx, "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Spec.Template.Annotations[restartedAtAnnotation] == "" {
+		t.Errorf("expected the pod template to be annotated, got %v", api.Spec.Template.Annotations)
+	}
+	web, err := client.AppsV1().Deployments("app").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := web.Spec.Template.Annotations[restartedAtAnnotation]; ok {
+		t.Errorf("expected the web service to be left as is")
+	}
+}
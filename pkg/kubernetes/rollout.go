@@ -0,0 +1,459 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// the waiting reasons of a container that will not recover on their own
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+}
+
+// the annotation of the revision of a deployment and its replicasets
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// the separator between the documents of a rendered helm manifest
+var manifestSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// define a workload created by a helm release
+type Workload struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// return the workload as kind/name
+func (w Workload) String() string {
+	return strings.ToLower(w.Kind) + "/" + w.Name
+}
+
+// the diagnostics of a pod that failed to start
+type PodDiagnostics struct {
+	Name      string
+	Container string
+	Reason    string
+	Message   string
+	Events    []string
+	Logs      []string
+}
+
+// define a failed rollout and the diagnostics of its failing pods
+type RolloutError struct {
+	Workload Workload
+	Reason   string
+	Pods     []*PodDiagnostics
+}
+
+// implement the error interface
+func (e *RolloutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s failed to roll out: %s", e.Workload, e.Reason)
+	for _, pod := range e.Pods {
+		fmt.Fprintf(&b, "\n  pod %s container %s: %s", pod.Name, pod.Container, pod.Reason)
+		if pod.Message != "" {
+			fmt.Fprintf(&b, " - %s", pod.Message)
+		}
+		if len(pod.Events) > 0 {
+			b.WriteString("\n    events:")
+			for _, event := range pod.Events {
+				fmt.Fprintf(&b, "\n      %s", event)
+			}
+		}
+		if len(pod.Logs) > 0 {
+			b.WriteString("\n    logs:")
+			for _, line := range pod.Logs {
+				fmt.Fprintf(&b, "\n      %s", line)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// list the deployments, statefulsets and jobs defined in a rendered helm manifest
+// workloads without a namespace default to the given namespace
+func ParseWorkloads(manifest string, namespace string) ([]Workload, error) {
+	workloads := []Workload{}
+	for _, doc := range manifestSeparator.Split(manifest, -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+
+		var object struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &object); err != nil {
+			return nil, err
+		}
+
+		switch object.Kind {
+		case "Deployment", "StatefulSet", "Job":
+			workload := Workload{Kind: object.Kind, Name: object.Metadata.Name, Namespace: object.Metadata.Namespace}
+			if workload.Namespace == "" {
+				workload.Namespace = namespace
+			}
+			workloads = append(workloads, workload)
+		}
+	}
+
+	return workloads, nil
+}
+
+// follow the rollout of workloads until they are ready
+type RolloutWatcher struct {
+	// the kubernetes client of the cluster
+	Client k8s.Interface
+
+	// how often the workloads are checked
+	Interval time.Duration
+
+	// the amount of log lines collected from a failing pod
+	LogLines int64
+
+	// called whenever a workload is not ready yet - optional
+	OnProgress func(workload Workload, status string)
+}
+
+// create a new rollout watcher with the default configs
+func NewRolloutWatcher(client k8s.Interface) *RolloutWatcher {
+	return &RolloutWatcher{
+		Client:   client,
+		Interval: 2 * time.Second,
+		LogLines: 20,
+	}
+}
+
+// wait until all the workloads are ready or the timeout is reached.
+// returns a RolloutError as soon as a workload is known to be failing
+func (w *RolloutWatcher) Wait(ctx context.Context, workloads []Workload, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pending := workloads
+	status := map[Workload]string{}
+	for len(pending) > 0 {
+		remaining := []Workload{}
+		for _, workload := range pending {
+			ready, message, err := w.check(ctx, workload)
+			if err != nil && ctx.Err() != nil {
+				return timeoutError(workload, timeout, status[workload])
+			} else if err != nil {
+				return err
+			}
+
+			if !ready {
+				status[workload] = message
+				remaining = append(remaining, workload)
+				if w.OnProgress != nil {
+					w.OnProgress(workload, message)
+				}
+			}
+		}
+		pending = remaining
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return timeoutError(pending[0], timeout, status[pending[0]])
+		case <-time.After(w.Interval):
+		}
+	}
+
+	return nil
+}
+
+// check whether a single workload is ready
+func (w *RolloutWatcher) check(ctx context.Context, workload Workload) (bool, string, error) {
+	switch workload.Kind {
+	case "Deployment":
+		return w.checkDeployment(ctx, workload)
+	case "StatefulSet":
+		return w.checkStatefulSet(ctx, workload)
+	case "Job":
+		return w.checkJob(ctx, workload)
+	default:
+		return true, "", nil
+	}
+}
+
+// check whether all the replicas of a deployment are updated and available
+func (w *RolloutWatcher) checkDeployment(ctx context.Context, workload Workload) (bool, string, error) {
+	deployment, err := w.Client.AppsV1().Deployments(workload.Namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, "", err
+	}
+
+	// only the pods of the new revision can fail the rollout, the pods
+	// of the previous revision may be failing when fixing forward
+	selector, err := w.deploymentRevisionSelector(ctx, deployment)
+	if err != nil {
+		return false, "", err
+	}
+	if err := w.checkPods(ctx, workload, selector); err != nil {
+		return false, "", err
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, "", &RolloutError{Workload: workload, Reason: condition.Message}
+		}
+	}
+
+	desired := replicas(deployment.Spec.Replicas)
+	status := deployment.Status
+	message := fmt.Sprintf("%d of %d replicas ready", status.AvailableReplicas, desired)
+	ready := status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas >= desired &&
+		status.Replicas == status.UpdatedReplicas &&
+		status.AvailableReplicas >= desired
+
+	return ready, message, nil
+}
+
+// check whether all the replicas of a statefulset are updated and ready
+func (w *RolloutWatcher) checkStatefulSet(ctx context.Context, workload Workload) (bool, string, error) {
+	statefulSet, err := w.Client.AppsV1().StatefulSets(workload.Namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, "", err
+	}
+
+	// only the pods of the update revision can fail the rollout
+	if err := w.checkPods(ctx, workload, statefulSetRevisionSelector(statefulSet)); err != nil {
+		return false, "", err
+	}
+
+	desired := replicas(statefulSet.Spec.Replicas)
+	status := statefulSet.Status
+	message := fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, desired)
+	ready := status.ObservedGeneration >= statefulSet.Generation && status.ReadyReplicas >= desired
+	if statefulSet.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		ready = ready && status.UpdatedReplicas >= desired
+	}
+
+	return ready, message, nil
+}
+
+// check whether a job completed
+func (w *RolloutWatcher) checkJob(ctx context.Context, workload Workload) (bool, string, error) {
+	job, err := w.Client.BatchV1().Jobs(workload.Namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, "", err
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, "", nil
+		case batchv1.JobFailed:
+			rolloutErr := &RolloutError{Workload: workload, Reason: condition.Message}
+			rolloutErr.Pods, _ = w.failedPods(ctx, workload, job.Spec.Selector, true)
+			return false, "", rolloutErr
+		}
+	}
+
+	if err := w.checkPods(ctx, workload, job.Spec.Selector); err != nil {
+		return false, "", err
+	}
+
+	completions := replicas(job.Spec.Completions)
+	return false, fmt.Sprintf("%d of %d completions", job.Status.Succeeded, completions), nil
+}
+
+// return the selector of the pods of the current replicaset of the deployment
+// nil until the deployment controller created the replicaset of the new revision
+func (w *RolloutWatcher) deploymentRevisionSelector(ctx context.Context, deployment *appsv1.Deployment) (*metav1.LabelSelector, error) {
+	revision := deployment.Annotations[deploymentRevisionAnnotation]
+	if deployment.Spec.Selector == nil || revision == "" || deployment.Status.ObservedGeneration < deployment.Generation {
+		return nil, nil
+	}
+
+	replicaSets, err := w.Client.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(deployment.Spec.Selector),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, replicaSet := range replicaSets.Items {
+		if !metav1.IsControlledBy(&replicaSet, deployment) || replicaSet.Annotations[deploymentRevisionAnnotation] != revision {
+			continue
+		}
+
+		hash, ok := replicaSet.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+		if !ok {
+			return nil, nil
+		}
+		return revisionSelector(deployment.Spec.Selector, appsv1.DefaultDeploymentUniqueLabelKey, hash), nil
+	}
+
+	return nil, nil
+}
+
+// return the selector of the pods of the update revision of the statefulset
+// nil until the statefulset controller observed the new revision
+func statefulSetRevisionSelector(statefulSet *appsv1.StatefulSet) *metav1.LabelSelector {
+	revision := statefulSet.Status.UpdateRevision
+	if statefulSet.Spec.Selector == nil || revision == "" || statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+		return nil
+	}
+
+	return revisionSelector(statefulSet.Spec.Selector, appsv1.StatefulSetRevisionLabel, revision)
+}
+
+// return a copy of the selector that also matches the revision label
+func revisionSelector(selector *metav1.LabelSelector, key string, revision string) *metav1.LabelSelector {
+	revisionSelector := selector.DeepCopy()
+	if revisionSelector.MatchLabels == nil {
+		revisionSelector.MatchLabels = make(map[string]string)
+	}
+	revisionSelector.MatchLabels[key] = revision
+
+	return revisionSelector
+}
+
+// returns a RolloutError if any of the workload pods is failing
+func (w *RolloutWatcher) checkPods(ctx context.Context, workload Workload, selector *metav1.LabelSelector) error {
+	pods, err := w.failedPods(ctx, workload, selector, false)
+	if err != nil {
+		return err
+	}
+
+	if len(pods) > 0 {
+		return &RolloutError{Workload: workload, Reason: pods[0].Reason, Pods: pods}
+	}
+
+	return nil
+}
+
+// collect the diagnostics of the workload pods that fail to start
+// terminated pods are included only when includeTerminated is set
+func (w *RolloutWatcher) failedPods(ctx context.Context, workload Workload, selector *metav1.LabelSelector, includeTerminated bool) ([]*PodDiagnostics, error) {
+	if selector == nil {
+		return nil, nil
+	}
+
+	pods, err := w.Client.CoreV1().Pods(workload.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(selector),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := []*PodDiagnostics{}
+	for _, pod := range pods.Items {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			diagnostic := &PodDiagnostics{Name: pod.Name, Container: status.Name}
+			switch {
+			case status.State.Waiting != nil && failedWaitingReasons[status.State.Waiting.Reason]:
+				diagnostic.Reason = status.State.Waiting.Reason
+				diagnostic.Message = status.State.Waiting.Message
+			case includeTerminated && status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+				diagnostic.Reason = status.State.Terminated.Reason
+				diagnostic.Message = fmt.Sprintf("exit code %d", status.State.Terminated.ExitCode)
+			default:
+				continue
+			}
+
+			diagnostic.Events = w.podEvents(ctx, &pod)
+			diagnostic.Logs = w.podLogs(ctx, &pod, status.Name, status.RestartCount > 0)
+			diagnostics = append(diagnostics, diagnostic)
+			break
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// return the recent events of the pod
+func (w *RolloutWatcher) podEvents(ctx context.Context, pod *corev1.Pod) []string {
+	events, err := w.Client.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.name=" + pod.Name,
+	})
+	if err != nil {
+		return nil
+	}
+
+	lines := []string{}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Name != pod.Name {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", event.Reason, event.Message))
+	}
+
+	return lastLines(lines, 5)
+}
+
+// return the last log lines of the container. the logs of the
+// previous run are returned for containers that restarted
+func (w *RolloutWatcher) podLogs(ctx context.Context, pod *corev1.Pod, container string, previous bool) []string {
+	raw, err := w.Client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &w.LogLines,
+		Previous:  previous,
+	}).DoRaw(ctx)
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+
+	return lastLines(lines, int(w.LogLines))
+}
+
+// return the error of a workload that did not become ready in time
+func timeoutError(workload Workload, timeout time.Duration, status string) *RolloutError {
+	reason := fmt.Sprintf("not ready after %s", timeout)
+	if status != "" {
+		reason += " - " + status
+	}
+
+	return &RolloutError{Workload: workload, Reason: reason}
+}
+
+// return the desired replicas - kubernetes defaults to a single replica
+func replicas(value *int32) int32 {
+	if value == nil {
+		return 1
+	}
+
+	return *value
+}
+
+// return at most the last n lines
+func lastLines(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+
+	return lines
+}
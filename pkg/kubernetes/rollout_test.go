@@ -0,0 +1,233 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const manifest = `---
+# Source: default-microservice/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+---
+# Source: default-microservice/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+---
+# Source: default-microservice/templates/job.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: api-migrate
+  namespace: jobs
+`
+
+var selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}
+
+// create a deployment at its second revision with the given available replicas
+func newDeployment(available int32) *appsv1.Deployment {
+	replicas := int32(2)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "app",
+			UID:         "api-uid",
+			Annotations: map[string]string{deploymentRevisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: &replicas, Selector: selector},
+		Status: appsv1.DeploymentStatus{
+			Replicas:          2,
+			UpdatedReplicas:   2,
+			AvailableReplicas: available,
+		},
+	}
+}
+
+// create the replicaset of a revision of the deployment
+func newReplicaSet(revision string, hash string) *appsv1.ReplicaSet {
+	controller := true
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "api-" + hash,
+			Namespace:       "app",
+			Labels:          map[string]string{"app": "api", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+			Annotations:     map[string]string{deploymentRevisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "api", UID: "api-uid", Controller: &controller}},
+		},
+	}
+}
+
+// create a pod of the current revision of the deployment with the given waiting reason
+func newPod(reason string) *corev1.Pod {
+	return newRevisionPod("api-1", "v2", reason)
+}
+
+// create a pod of the revision with the given waiting reason
+func newRevisionPod(name string, hash string, reason string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app", Labels: map[string]string{"app": "api", appsv1.DefaultDeploymentUniqueLabelKey: hash}},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "api",
+				RestartCount: 3,
+				State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}},
+			}},
+		},
+	}
+}
+
+// create a watcher for the given cluster objects
+func newWatcher(objects ...runtime.Object) *RolloutWatcher {
+	watcher := NewRolloutWatcher(fake.NewSimpleClientset(objects...))
+	watcher.Interval = 10 * time.Millisecond
+	return watcher
+}
+
+// TestParseWorkloads lists only the workloads of the manifest
+func TestParseWorkloads(t *testing.T) {
+	workloads, err := ParseWorkloads(manifest, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Workload{
+		{Kind: "Deployment", Name: "api", Namespace: "app"},
+		{Kind: "Job", Name: "api-migrate", Namespace: "jobs"},
+	}
+	if len(workloads) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, workloads)
+	}
+	for i := range expected {
+		if workloads[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], workloads[i])
+		}
+	}
+}
+
+// TestWaitReady returns once the deployment is available
+func TestWaitReady(t *testing.T) {
+	watcher := newWatcher(newDeployment(2), newReplicaSet("2", "v2"), newPod(""))
+	workloads := []Workload{{Kind: "Deployment", Name: "api", Namespace: "app"}}
+	if err := watcher.Wait(context.Background(), workloads, time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWaitTimeout fails when the deployment does not become available in time
+func TestWaitTimeout(t *testing.T) {
+	watcher := newWatcher(newDeployment(1))
+	workloads := []Workload{{Kind: "Deployment", Name: "api", Namespace: "app"}}
+	err := watcher.Wait(context.Background(), workloads, 50*time.Millisecond)
+
+	var rolloutErr *RolloutError
+	if !errors.As(err, &rolloutErr) || !strings.Contains(rolloutErr.Reason, "1 of 2 replicas ready") {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+}
+
+// TestWaitCrashLoop reports the failing pod with its events and logs
+func TestWaitCrashLoop(t *testing.T) {
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "api-1.backoff", Namespace: "app"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "api-1"},
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container",
+	}
+	watcher := newWatcher(newDeployment(1), newReplicaSet("2", "v2"), newPod("CrashLoopBackOff"), event)
+	workloads := []Workload{{Kind: "Deployment", Name: "api", Namespace: "app"}}
+	err := watcher.Wait(context.Background(), workloads, time.Second)
+
+	var rolloutErr *RolloutError
+	if !errors.As(err, &rolloutErr) {
+		t.Fatalf("expected a rollout error, got %v", err)
+	}
+	if rolloutErr.Reason != "CrashLoopBackOff" || len(rolloutErr.Pods) != 1 {
+		t.Fatalf("expected a single crashing pod, got %+v", rolloutErr)
+	}
+
+	pod := rolloutErr.Pods[0]
+	if len(pod.Events) != 1 || pod.Events[0] != "BackOff: Back-off restarting failed container" {
+		t.Errorf("expected the pod events, got %v", pod.Events)
+	}
+	if len(pod.Logs) == 0 {
+		t.Errorf("expected the pod logs, got none")
+	}
+}
+
+// TestWaitIgnoresPreviousRevision fixes forward a crashing previous revision
+func TestWaitIgnoresPreviousRevision(t *testing.T) {
+	watcher := newWatcher(
+		newDeployment(2),
+		newReplicaSet("1", "v1"),
+		newReplicaSet("2", "v2"),
+		newRevisionPod("api-old", "v1", "CrashLoopBackOff"),
+		newPod(""),
+	)
+	workloads := []Workload{{Kind: "Deployment", Name: "api", Namespace: "app"}}
+	if err := watcher.Wait(context.Background(), workloads, time.Second); err != nil {
+		t.Fatalf("expected the previous revision to be ignored, got %v", err)
+	}
+}
+
+// TestWaitStatefulSetRevision only fails on the pods of the update revision
+func TestWaitStatefulSetRevision(t *testing.T) {
+	replicas := int32(1)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "app"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas, Selector: selector},
+		Status:     appsv1.StatefulSetStatus{UpdateRevision: "api-v2"},
+	}
+	pod := func(name string, revision string, reason string) *corev1.Pod {
+		pod := newRevisionPod(name, "", reason)
+		pod.Labels = map[string]string{"app": "api", appsv1.StatefulSetRevisionLabel: revision}
+		return pod
+	}
+	workloads := []Workload{{Kind: "StatefulSet", Name: "api", Namespace: "app"}}
+
+	watcher := newWatcher(statefulSet, pod("api-0", "api-v1", "CrashLoopBackOff"))
+	var rolloutErr *RolloutError
+	if err := watcher.Wait(context.Background(), workloads, 50*time.Millisecond); !errors.As(err, &rolloutErr) || rolloutErr.Reason == "CrashLoopBackOff" {
+		t.Errorf("expected the previous revision to be ignored until the timeout, got %v", err)
+	}
+
+	watcher = newWatcher(statefulSet, pod("api-0", "api-v2", "CrashLoopBackOff"))
+	if err := watcher.Wait(context.Background(), workloads, time.Second); !errors.As(err, &rolloutErr) || rolloutErr.Reason != "CrashLoopBackOff" {
+		t.Errorf("expected the update revision to fail the rollout, got %v", err)
+	}
+}
+
+// TestWaitJobFailed fails when the job failed
+func TestWaitJobFailed(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-migrate", Namespace: "app"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{
+				Type:    batchv1.JobFailed,
+				Status:  corev1.ConditionTrue,
+				Message: "Job has reached the specified backoff limit",
+			}},
+		},
+	}
+	watcher := newWatcher(job)
+	workloads := []Workload{{Kind: "Job", Name: "api-migrate", Namespace: "app"}}
+	err := watcher.Wait(context.Background(), workloads, time.Second)
+
+	var rolloutErr *RolloutError
+	if !errors.As(err, &rolloutErr) || !strings.Contains(rolloutErr.Reason, "backoff limit") {
+		t.Fatalf("expected a failed job error, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package kubernetes
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// create the secret or replace the data of the existing one
+func ApplySecret(ctx context.Context, client k8s.Interface, secret *corev1.Secret) error {
+	secrets := client.CoreV1().Secrets(secret.Namespace)
+
+	existing, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.Labels = secret.Labels
+	existing.Data = secret.Data
+	existing.StringData = secret.StringData
+	_, err = secrets.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// create the secret unless it already exists, the existing secret is kept
+// as is. returns true if the secret was created
+func CreateSecretIfMissing(ctx context.Context, client k8s.Interface, secret *corev1.Secret) (bool, error) {
+	_, err := client.CoreV1().Secrets(secret.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// delete the secrets with the given labels except the one to keep
+func PruneSecrets(ctx context.Context, client k8s.Interface, namespace string, selector map[string]string, keep string) error {
+	secrets := client.CoreV1().Secrets(namespace)
+
+	list, err := secrets.List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range list.Items {
+		if secret.Name == keep {
+			continue
+		}
+		if err := secrets.Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
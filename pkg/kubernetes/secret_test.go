@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newSecret returns a secret of the api service
+func newSecret(name string, data map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app", Labels: map[string]string{"service": "api"}},
+		StringData: data,
+	}
+}
+
+// TestApplySecret creates the secret and updates it on the next apply
+func TestApplySecret(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	if err := ApplySecret(ctx, client, newSecret("api-secrets", map[string]string{"API_KEY": "1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplySecret(ctx, client, newSecret("api-secrets", map[string]string{"API_KEY": "2"})); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := client.CoreV1().Secrets("app").Get(ctx, "api-secrets", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.StringData["API_KEY"] != "2" {
+		t.Errorf("expected the data to be updated, got %v", secret.StringData)
+	}
+}
+
+// TestPruneSecrets removes the previous secrets of the service
+func TestPruneSecrets(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(newSecret("api-secrets-1", nil), newSecret("api-secrets-2", nil))
+
+	if err := PruneSecrets(ctx, client, "app", map[string]string{"service": "api"}, "api-secrets-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := client.CoreV1().Secrets("app").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "api-secrets-2" {
+		t.Errorf("expected only the current secret to be kept, got %v", list.Items)
+	}
+}
+
+// TestCreateSecretIfMissing keeps the data of the existing secret
+func TestCreateSecretIfMissing(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	created, err := CreateSecretIfMissing(ctx, client, newSecret("db", map[string]string{"password": "1"}))
+	if err != nil || !created {
+		t.Fatalf("expected the secret to be created, got %v, %v", created, err)
+	}
+	created, err = CreateSecretIfMissing(ctx, client, newSecret("db", map[string]string{"password": "2"}))
+	if err != nil || created {
+		t.Fatalf("expected the existing secret to be kept, got %v, %v", created, err)
+	}
+
+	secret, err := client.CoreV1().Secrets("app").Get(ctx, "db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.StringData["password"] != "1" {
+		t.Errorf("expected the original data, got %v", secret.StringData)
+	}
+}
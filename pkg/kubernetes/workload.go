@@ -0,0 +1,32 @@
+package kubernetes
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// return the kind of the deployment or stateful set with the given name and
+// the claim templates of a stateful set. the kind is empty if neither exists
+func GetWorkloadKind(ctx context.Context, client k8s.Interface, namespace string, name string) (string, []corev1.PersistentVolumeClaim, error) {
+	_, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return "Deployment", nil, nil
+	}
+	if !errors.IsNotFound(err) {
+		return "", nil, err
+	}
+
+	statefulSet, err := client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "StatefulSet", statefulSet.Spec.VolumeClaimTemplates, nil
+}
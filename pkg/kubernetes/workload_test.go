@@ -0,0 +1,37 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestGetWorkloadKind finds the deployment or the stateful set of the release
+func TestGetWorkloadKind(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "app"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "app"}, Spec: appsv1.StatefulSetSpec{
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}},
+		}},
+	)
+
+	kind, claims, err := GetWorkloadKind(ctx, client, "app", "api")
+	if err != nil || kind != "Deployment" || claims != nil {
+		t.Errorf("expected a deployment, got %s %v %v", kind, claims, err)
+	}
+
+	kind, claims, err = GetWorkloadKind(ctx, client, "app", "worker")
+	if err != nil || kind != "StatefulSet" || len(claims) != 1 || claims[0].Name != "data" {
+		t.Errorf("expected a stateful set with a data claim template, got %s %v %v", kind, claims, err)
+	}
+
+	kind, _, err = GetWorkloadKind(ctx, client, "app", "web")
+	if err != nil || kind != "" {
+		t.Errorf("expected no workload, got %s %v", kind, err)
+	}
+}
@@ -9,10 +9,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	log     *logrus.Entry
-	logsnag *gologsnag.LogSnag
-)
+var log *logrus.Entry
 
 // define a global session id to identify the flow
 var sessionID = uuid.Must(uuid.NewV4()).String()
@@ -21,16 +18,7 @@ var sessionID = uuid.Must(uuid.NewV4()).String()
 // log level and format
 func init() {
 	initLogrusLogger()
-	initLogsnagLogger()
-}
-
-// initialize the logsnag logger
-func initLogsnagLogger() {
-	logsnag = gologsnag.NewLogSnag(
-		// public nopeus logsnag key
-		"2f0420e7710703268ea2ab32f493c887",
-		"salfati-group-cloud",
-	)
+	initTelemetry()
 }
 
 // initialize the logrus logger
@@ -44,7 +32,8 @@ func initLogrusLogger() {
 
 	// set the format of the logger
 	// base on the GO_ENV environment variable
-	l.SetFormatter(getLogFormat())
+	// and mask any sensitive value before it's written
+	l.SetFormatter(&redactingFormatter{formatter: getLogFormat()})
 
 	// define the default logger fields
 	rl := l.WithFields(logrus.Fields{
@@ -149,43 +138,58 @@ type PublishError struct {
 	Error error `json:"error"`
 }
 
-// export the logsnag publish function
+// publish an event to the telemetry sink
+// events are kept in memory until the telemetry is configured
 func Publish(options *gologsnag.PublishOptions) *PublishError {
-	if os.Getenv("GO_ENV") != "development" {
-		ctx := context.Background()
+	if queueTelemetryEvent(telemetryEvent{publish: options}) {
+		return nil
+	}
 
-		// if tags do not exists, init empty tags
-		if options.Tags == nil {
-			options.Tags = &gologsnag.Tags{}
-		}
+	sink := getTelemetrySink()
+	if _, ok := sink.(*NoopSink); ok {
+		return nil
+	}
+
+	// if tags do not exists, init empty tags
+	if options.Tags == nil {
+		options.Tags = &gologsnag.Tags{}
+	}
 
-		// extend the tags with the session id
-		options.Tags.Add("session-id", sessionID)
+	// extend the tags with the session id
+	options.Tags.Add("session-id", sessionID)
 
-		// force the channel to be "nopeus-public"
-		options.Channel = "nopeus-public"
+	// force the channel to be "nopeus-public"
+	options.Channel = "nopeus-public"
 
-		// publish the logsnag message
-		err := logsnag.Publish(ctx, options)
-		return &PublishError{
-			Error: err,
-		}
+	// publish the telemetry event
+	err := sink.Publish(context.Background(), options)
+	if err != nil {
+		Debugf("failed to publish telemetry event: %s", err)
 	}
 
-	return nil
+	return &PublishError{
+		Error: err,
+	}
 }
 
-// export the logsnag insight function
+// send an insight to the telemetry sink
+// insights are kept in memory until the telemetry is configured
 func Insight(options *gologsnag.InsightOptions) *PublishError {
-	if os.Getenv("GO_ENV") != "development" {
-		ctx := context.Background()
-
-		// send an insight to logsnag
-		err := logsnag.Insight(ctx, options)
-		return &PublishError{
-			Error: err,
-		}
+	if queueTelemetryEvent(telemetryEvent{insight: options}) {
+		return nil
+	}
+
+	sink := getTelemetrySink()
+	if _, ok := sink.(*NoopSink); ok {
+		return nil
 	}
 
-	return nil
+	err := sink.Insight(context.Background(), options)
+	if err != nil {
+		Debugf("failed to send telemetry insight: %s", err)
+	}
+
+	return &PublishError{
+		Error: err,
+	}
 }
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the mask that replaces sensitive values in the logs
+const RedactedValue = "[REDACTED]"
+
+// values shorter than this are not registered to avoid
+// masking common words (e.g., "true", "prod")
+const minSensitiveLength = 4
+
+// match the keys that usually hold secret values
+var secretKeyRegex = regexp.MustCompile(`(?i)(password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credential|auth|dsn|database_url|connection_string)`)
+
+var (
+	// the values that should never be printed, sorted by length
+	// so longer values are masked before their substrings
+	sensitiveValues []string
+	sensitiveMutex  sync.RWMutex
+)
+
+// register values that should be masked in any log line
+func RegisterSensitive(values ...string) {
+	sensitiveMutex.Lock()
+	defer sensitiveMutex.Unlock()
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) < minSensitiveLength || containsString(sensitiveValues, value) {
+			continue
+		}
+
+		sensitiveValues = append(sensitiveValues, value)
+	}
+
+	sort.Slice(sensitiveValues, func(i, j int) bool {
+		return len(sensitiveValues[i]) > len(sensitiveValues[j])
+	})
+}
+
+// returns true if the key usually holds a secret value
+func IsSecretKey(key string) bool {
+	return secretKeyRegex.MatchString(key)
+}
+
+// mask every registered sensitive value in the given string
+func Redact(s string) string {
+	sensitiveMutex.RLock()
+	defer sensitiveMutex.RUnlock()
+
+	for _, value := range sensitiveValues {
+		s = strings.ReplaceAll(s, value, RedactedValue)
+	}
+
+	return s
+}
+
+// return true if the slice contains the given string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// a logrus formatter that masks the sensitive values
+// before the entry is formatted by the wrapped formatter
+type redactingFormatter struct {
+	formatter logrus.Formatter
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	// mask the raw message and fields - the formatters may escape
+	// the values which hides them from the formatted output check
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		if str, ok := value.(string); ok {
+			entry.Data[key] = Redact(str)
+		}
+	}
+
+	b, err := f.formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(Redact(string(b))), nil
+}
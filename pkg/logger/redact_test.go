@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestRedactRegisteredValues masks the registered values
+func TestRedactRegisteredValues(t *testing.T) {
+	RegisterSensitive("s3cr3t-password", "abc")
+
+	redacted := Redact("DB_PASSWORD=s3cr3t-password MODE=abc")
+	if strings.Contains(redacted, "s3cr3t-password") {
+		t.Errorf("expected the password to be redacted, got %s", redacted)
+	}
+
+	// short values are never registered
+	if !strings.Contains(redacted, "MODE=abc") {
+		t.Errorf("expected short values to be kept, got %s", redacted)
+	}
+}
+
+// TestRedactingFormatter masks the values before they are escaped
+func TestRedactingFormatter(t *testing.T) {
+	RegisterSensitive(`p"ss\word`)
+
+	out := &bytes.Buffer{}
+	l := logrus.New()
+	l.SetOutput(out)
+	l.SetLevel(logrus.DebugLevel)
+	l.SetFormatter(&redactingFormatter{formatter: &logrus.TextFormatter{DisableColors: true}})
+	l.WithField("value", `p"ss\word`).Debugf("value: %s", `p"ss\word`)
+
+	if strings.Contains(out.String(), "word") {
+		t.Errorf("expected the value to be redacted, got %s", out.String())
+	}
+}
+
+// TestIsSecretKey matches the common secret key names
+func TestIsSecretKey(t *testing.T) {
+	for key, expected := range map[string]bool{
+		"DB_PASSWORD":    true,
+		"STRIPE_API_KEY": true,
+		"GITHUB_TOKEN":   true,
+		"PORT":           false,
+		"LOG_LEVEL":      false,
+	} {
+		if IsSecretKey(key) != expected {
+			t.Errorf("expected IsSecretKey(%s) to be %v", key, expected)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/salfatigroup/gologsnag"
+)
+
+// define the supported telemetry sinks
+const (
+	TelemetrySinkLogSnag = "logsnag"
+	TelemetrySinkFile    = "file"
+	TelemetrySinkHTTP    = "http"
+	TelemetrySinkNone    = "none"
+)
+
+// the interface every telemetry sink implements
+type TelemetrySink interface {
+	Publish(ctx context.Context, options *gologsnag.PublishOptions) error
+	Insight(ctx context.Context, options *gologsnag.InsightOptions) error
+}
+
+// define the telemetry options as defined by the user
+type TelemetryOptions struct {
+	// is telemetry enabled
+	Enabled bool
+
+	// the sink to send the events to - logsnag, file, http or none
+	Sink string
+
+	// the file location for the file sink
+	Path string
+
+	// the endpoint for the http sink
+	URL string
+}
+
+// a pending telemetry event, waiting for the telemetry to be configured
+type telemetryEvent struct {
+	publish *gologsnag.PublishOptions
+	insight *gologsnag.InsightOptions
+}
+
+var (
+	telemetrySink  TelemetrySink = &NoopSink{}
+	telemetryReady bool
+	pendingEvents  []telemetryEvent
+	telemetryMutex sync.Mutex
+)
+
+// initialize the telemetry sink. events are kept in memory until the
+// user configs are loaded, unless telemetry is turned off by the environment
+func initTelemetry() {
+	if isTelemetryDisabledByEnv() {
+		telemetryReady = true
+	}
+}
+
+// returns true if NOPEUS_TELEMETRY turns the telemetry off
+func isTelemetryDisabledByEnv() bool {
+	if os.Getenv("GO_ENV") == "development" {
+		return true
+	}
+
+	switch strings.ToLower(os.Getenv("NOPEUS_TELEMETRY")) {
+	case "off", "false", "0", "no", "disabled":
+		return true
+	default:
+		return false
+	}
+}
+
+// configure the telemetry sink based on the user configs
+// NOPEUS_TELEMETRY=off always takes precedence over the configs
+func ConfigureTelemetry(options TelemetryOptions) error {
+	var sink TelemetrySink
+	switch {
+	case isTelemetryDisabledByEnv() || !options.Enabled:
+		sink = &NoopSink{}
+	case options.Sink == "" || options.Sink == TelemetrySinkLogSnag:
+		sink = NewLogSnagSink()
+		showTelemetryNotice()
+	case options.Sink == TelemetrySinkFile:
+		if options.Path == "" {
+			return fmt.Errorf("telemetry file sink requires a path")
+		}
+		sink = NewFileSink(options.Path)
+	case options.Sink == TelemetrySinkHTTP:
+		if options.URL == "" {
+			return fmt.Errorf("telemetry http sink requires a url")
+		}
+		sink = NewHTTPSink(options.URL)
+		showTelemetryNotice()
+	case options.Sink == TelemetrySinkNone:
+		sink = &NoopSink{}
+	default:
+		return fmt.Errorf("unsupported telemetry sink %s", options.Sink)
+	}
+
+	SetTelemetrySink(sink)
+	return nil
+}
+
+// replace the telemetry sink and send any pending events to it
+func SetTelemetrySink(sink TelemetrySink) {
+	telemetryMutex.Lock()
+	telemetrySink = sink
+	telemetryReady = true
+	pending := pendingEvents
+	pendingEvents = nil
+	telemetryMutex.Unlock()
+
+	for _, event := range pending {
+		if event.publish != nil {
+			Publish(event.publish)
+		} else if event.insight != nil {
+			Insight(event.insight)
+		}
+	}
+}
+
+// return the current telemetry sink
+// returns nil until the telemetry is configured
+func getTelemetrySink() TelemetrySink {
+	telemetryMutex.Lock()
+	defer telemetryMutex.Unlock()
+
+	if !telemetryReady {
+		return nil
+	}
+
+	return telemetrySink
+}
+
+// keep the event until the telemetry is configured
+// returns false if the telemetry is already configured
+func queueTelemetryEvent(event telemetryEvent) bool {
+	telemetryMutex.Lock()
+	defer telemetryMutex.Unlock()
+
+	if telemetryReady {
+		return false
+	}
+
+	pendingEvents = append(pendingEvents, event)
+	return true
+}
+
+// let the user know about the telemetry on the first run
+func showTelemetryNotice() {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return
+	}
+
+	marker := filepath.Join(configDir, "nopeus", "telemetry-notice")
+	if _, err := os.Stat(marker); err == nil {
+		return
+	}
+
+	// write to stderr to keep the stdout json output valid
+	fmt.Fprintln(os.Stderr, "nopeus collects anonymous usage events (commands, errors and environment names) to improve the product.")
+	fmt.Fprintln(os.Stderr, "Turn it off with NOPEUS_TELEMETRY=off or `telemetry: { enabled: false }` in nopeus.yaml.")
+
+	if err := os.MkdirAll(filepath.Dir(marker), 0o755); err != nil {
+		return
+	}
+	os.WriteFile(marker, []byte(time.Now().UTC().Format(time.RFC3339)), 0o644)
+}
+
+// a sink that drops every event
+type NoopSink struct{}
+
+func (s *NoopSink) Publish(ctx context.Context, options *gologsnag.PublishOptions) error {
+	return nil
+}
+
+func (s *NoopSink) Insight(ctx context.Context, options *gologsnag.InsightOptions) error {
+	return nil
+}
+
+// a sink that sends the events to the nopeus logsnag project
+type LogSnagSink struct {
+	client *gologsnag.LogSnag
+}
+
+// create a new logsnag sink
+func NewLogSnagSink() *LogSnagSink {
+	return &LogSnagSink{
+		client: gologsnag.NewLogSnag(
+			// public nopeus logsnag key
+			"2f0420e7710703268ea2ab32f493c887",
+			"salfati-group-cloud",
+		),
+	}
+}
+
+func (s *LogSnagSink) Publish(ctx context.Context, options *gologsnag.PublishOptions) error {
+	return s.client.Publish(ctx, options)
+}
+
+func (s *LogSnagSink) Insight(ctx context.Context, options *gologsnag.InsightOptions) error {
+	return s.client.Insight(ctx, options)
+}
+
+// define a single telemetry record written by the file and http sinks
+type TelemetryRecord struct {
+	Type    string                    `json:"type"`
+	Time    time.Time                 `json:"time"`
+	Publish *gologsnag.PublishOptions `json:"publish,omitempty"`
+	Insight *gologsnag.InsightOptions `json:"insight,omitempty"`
+}
+
+// a sink that appends the events as json lines to a local file
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// create a new file sink
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (s *FileSink) Publish(ctx context.Context, options *gologsnag.PublishOptions) error {
+	return s.write(&TelemetryRecord{Type: "publish", Time: time.Now().UTC(), Publish: options})
+}
+
+func (s *FileSink) Insight(ctx context.Context, options *gologsnag.InsightOptions) error {
+	return s.write(&TelemetryRecord{Type: "insight", Time: time.Now().UTC(), Insight: options})
+}
+
+// append a single record to the file
+func (s *FileSink) write(record *TelemetryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(record)
+}
+
+// a sink that posts the events as json to an http endpoint
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// create a new http sink
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, options *gologsnag.PublishOptions) error {
+	return s.post(ctx, &TelemetryRecord{Type: "publish", Time: time.Now().UTC(), Publish: options})
+}
+
+func (s *HTTPSink) Insight(ctx context.Context, options *gologsnag.InsightOptions) error {
+	return s.post(ctx, &TelemetryRecord{Type: "insight", Time: time.Now().UTC(), Insight: options})
+}
+
+// post a single record to the endpoint
+func (s *HTTPSink) post(ctx context.Context, record *TelemetryRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/salfatigroup/gologsnag"
+)
+
+// a sink that records the events it receives
+type recordingSink struct {
+	events   []*gologsnag.PublishOptions
+	insights []*gologsnag.InsightOptions
+}
+
+func (s *recordingSink) Publish(ctx context.Context, options *gologsnag.PublishOptions) error {
+	s.events = append(s.events, options)
+	return nil
+}
+
+func (s *recordingSink) Insight(ctx context.Context, options *gologsnag.InsightOptions) error {
+	s.insights = append(s.insights, options)
+	return nil
+}
+
+// reset the telemetry state between tests
+func resetTelemetry() {
+	telemetryMutex.Lock()
+	defer telemetryMutex.Unlock()
+
+	telemetrySink = &NoopSink{}
+	telemetryReady = false
+	pendingEvents = nil
+}
+
+// TestPublishQueuesUntilConfigured sends the pending events once the sink is set
+func TestPublishQueuesUntilConfigured(t *testing.T) {
+	resetTelemetry()
+	t.Setenv("GO_ENV", "")
+	t.Setenv("NOPEUS_TELEMETRY", "")
+
+	Publish(&gologsnag.PublishOptions{Event: "liftoff"})
+	Insight(&gologsnag.InsightOptions{Title: "deployed-apps", Value: 1})
+
+	sink := &recordingSink{}
+	SetTelemetrySink(sink)
+
+	if len(sink.events) != 1 || sink.events[0].Event != "liftoff" {
+		t.Fatalf("expected the pending liftoff event, got %+v", sink.events)
+	}
+	if sink.events[0].Channel != "nopeus-public" {
+		t.Errorf("expected the nopeus-public channel, got %s", sink.events[0].Channel)
+	}
+	if (*sink.events[0].Tags)["session-id"] != sessionID {
+		t.Errorf("expected the session id tag, got %+v", sink.events[0].Tags)
+	}
+	if len(sink.insights) != 1 {
+		t.Errorf("expected the pending insight, got %+v", sink.insights)
+	}
+}
+
+// TestTelemetryOptOut drops the events when NOPEUS_TELEMETRY=off
+func TestTelemetryOptOut(t *testing.T) {
+	resetTelemetry()
+	t.Setenv("GO_ENV", "")
+	t.Setenv("NOPEUS_TELEMETRY", "off")
+
+	if err := ConfigureTelemetry(TelemetryOptions{Enabled: true, Sink: TelemetrySinkHTTP, URL: "http://localhost"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := getTelemetrySink().(*NoopSink); !ok {
+		t.Errorf("expected the noop sink, got %T", getTelemetrySink())
+	}
+}
+
+// TestTelemetryDisabledByConfig drops the events when disabled in the configs
+func TestTelemetryDisabledByConfig(t *testing.T) {
+	resetTelemetry()
+	t.Setenv("GO_ENV", "")
+	t.Setenv("NOPEUS_TELEMETRY", "")
+
+	Publish(&gologsnag.PublishOptions{Event: "liftoff"})
+	if err := ConfigureTelemetry(TelemetryOptions{Enabled: false}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := getTelemetrySink().(*NoopSink); !ok {
+		t.Errorf("expected the noop sink, got %T", getTelemetrySink())
+	}
+	if len(pendingEvents) != 0 {
+		t.Errorf("expected the pending events to be dropped")
+	}
+}
@@ -31,7 +31,7 @@ func manualHelmCommands(cfg *config.NopeusConfig, envName string, envData *confi
 	}
 
 	// install cert-manager manually
-	fmt.Println(util.GrayText("Installing cert-manager..."))
+	util.Message("Installing cert-manager...")
 
 	// get client pointing to cert-manager namespace
 	helmClient, err := helm.NewHelmClient("cert-manager", kubeContext)
@@ -85,6 +85,7 @@ func (p *CertManagerPlugin) RunBeforeGenerate(cfg *config.NopeusConfig, envName
 			},
 		},
 	}
+	service.SetInfrastructure(true)
 
 	cfg.Runtime.HelmRuntime.ServiceTemplateData = append(cfg.Runtime.HelmRuntime.ServiceTemplateData, service)
 
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/templates"
 )
 
 type ChecksumPlugin struct{}
@@ -14,14 +15,36 @@ func (p *ChecksumPlugin) Name() string {
 	return "nopeus-checksum"
 }
 
-// define the plugin logic
 func (p *ChecksumPlugin) RunBeforeGenerate(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
-	cloudVendor, err := cfg.CAL.GetCloudVendor()
-	if err != nil {
-		return err
+	return nil
+}
+
+func (p *ChecksumPlugin) RunOnInit(cfg *config.NopeusConfig) error {
+	return nil
+}
+
+func (p *ChecksumPlugin) RunAfterGenerate(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+	return nil
+}
+
+func (p *ChecksumPlugin) RunBeforeDeploy(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+	return nil
+}
+
+// store the checksums of the services that were deployed successfully
+// so unchanged services are skipped on the next liftoff
+func (p *ChecksumPlugin) RunAfterDeploy(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+	return StoreChecksums(cfg, envName, envData)
+}
+
+// store the checksums of the services that reached a healthy state in the
+// checksum release. also used when a later service fails the deployment
+func StoreChecksums(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+	if cfg.Runtime.DryRun || len(envData.GetChecksumMap()) == 0 {
+		return nil
 	}
 
-	checksumMap, err := generateChecksumMap(cfg.Runtime.HelmRuntime.ServiceTemplateData)
+	cloudVendor, err := cfg.CAL.GetCloudVendor()
 	if err != nil {
 		return err
 	}
@@ -36,43 +59,16 @@ func (p *ChecksumPlugin) RunBeforeGenerate(cfg *config.NopeusConfig, envName str
 		DryRun:         cfg.Runtime.DryRun,
 		Values: &config.HelmRendererValues{
 			Custom: map[string]interface{}{
-				"Checksum": checksumMap,
+				"Checksum": envData.GetChecksumMap(),
 			},
 		},
 	}
 
-	cfg.Runtime.HelmRuntime.ServiceTemplateData = append(cfg.Runtime.HelmRuntime.ServiceTemplateData, service)
-
-	return nil
-}
-
-// generate the chcecksum map for all the given services
-func generateChecksumMap(services []config.ServiceTemplateData) (map[string]string, error) {
-	checksumMap := make(map[string]string)
-	for _, service := range services {
-		checksum, err := service.GetChecksum()
-		if err != nil {
-			return nil, err
-		}
-		checksumMap[service.GetName()] = checksum
+	if err := templates.RenderHelmTemplateFile(service); err != nil {
+		return err
 	}
-	return checksumMap, nil
-}
-
-func (p *ChecksumPlugin) RunOnInit(cfg *config.NopeusConfig) error {
-	return nil
-}
 
-func (p *ChecksumPlugin) RunAfterGenerate(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
-	return nil
-}
-
-func (p *ChecksumPlugin) RunBeforeDeploy(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
-	return nil
-}
-
-func (p *ChecksumPlugin) RunAfterDeploy(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
-	return nil
+	return service.ApplyHelmChart(envData.GetKubeContext())
 }
 
 func (p *ChecksumPlugin) RunOnFinish(cfg *config.NopeusConfig) error {
@@ -1,6 +1,9 @@
 package plugins
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/salfatigroup/nopeus/config"
 	"github.com/salfatigroup/nopeus/helm"
 	helmrepo "helm.sh/helm/v3/pkg/repo"
@@ -35,11 +38,62 @@ func (p *PrometheusPlugin) RunBeforeGenerate(cfg *config.NopeusConfig, envName s
 		HelmPackage: "prometheus-community/kube-prometheus-stack",
 		Namespace:   "nopeus",
 	}
+	service.SetInfrastructure(true)
 
 	cfg.Runtime.HelmRuntime.ServiceTemplateData = append(cfg.Runtime.HelmRuntime.ServiceTemplateData, service)
+
+	// serve the prometheus metrics to the horizontal pod autoscalers
+	// only when a service scales on a custom metric
+	if !usesCustomMetrics(cfg) {
+		return nil
+	}
+
+	cloudVendor, err := cfg.CAL.GetCloudVendor()
+	if err != nil {
+		return err
+	}
+
+	workingDir := filepath.Join(cfg.Runtime.TmpFileLocation, cloudVendor, envName)
+	adapter := &config.NopeusDefaultMicroservice{
+		Name:           "prometheus-adapter",
+		HelmPackage:    "prometheus-community/prometheus-adapter",
+		ValuesTemplate: "prometheus-adapter.values.yaml",
+		ValuesPath:     fmt.Sprintf("%s/prometheus-adapter.values.yaml", workingDir),
+		Namespace:      "nopeus",
+		DryRun:         cfg.Runtime.DryRun,
+		Values: &config.HelmRendererValues{
+			Name: "prometheus-adapter",
+			Custom: map[string]interface{}{
+				"PrometheusURL":  "http://prometheus-kube-prometheus-prometheus.nopeus.svc",
+				"PrometheusPort": 9090,
+			},
+		},
+	}
+	adapter.SetInfrastructure(true)
+
+	// the adapter serves the metrics of the prometheus release
+	adapter.SetDependencies([]string{service.GetName()})
+
+	cfg.Runtime.HelmRuntime.ServiceTemplateData = append(cfg.Runtime.HelmRuntime.ServiceTemplateData, adapter)
 	return nil
 }
 
+// return true if any of the services scales on a custom metric
+func usesCustomMetrics(cfg *config.NopeusConfig) bool {
+	services, err := cfg.CAL.GetServices()
+	if err != nil {
+		return false
+	}
+
+	for _, service := range services {
+		if autoscaling := service.GetAutoscaling(); autoscaling != nil && autoscaling.Metric != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p *PrometheusPlugin) RunAfterGenerate(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
 	return nil
 }
@@ -1,8 +1,11 @@
 package plugins
 
 import (
+	"context"
+
 	"github.com/salfatigroup/nopeus/config"
 	"github.com/salfatigroup/nopeus/logger"
+	"github.com/salfatigroup/nopeus/tracing"
 )
 
 type Plugin interface {
@@ -46,9 +49,9 @@ func RegisterPlugins() {
 }
 
 // run all on init functions for all plugins
-func RunOnInit(cfg *config.NopeusConfig) error {
+func RunOnInit(ctx context.Context, cfg *config.NopeusConfig) error {
 	for _, plugin := range PluginManagerInstance.plugins {
-		if err := plugin.RunOnInit(cfg); err != nil {
+		if err := runHook(ctx, plugin, "on-init", func() error { return plugin.RunOnInit(cfg) }); err != nil {
 			return err
 		}
 	}
@@ -56,9 +59,9 @@ func RunOnInit(cfg *config.NopeusConfig) error {
 }
 
 // run all before generate functions for all plugins
-func RunBeforeGenerate(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+func RunBeforeGenerate(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
 	for _, plugin := range PluginManagerInstance.plugins {
-		if err := plugin.RunBeforeGenerate(cfg, envName, envData); err != nil {
+		if err := runHook(ctx, plugin, "before-generate", func() error { return plugin.RunBeforeGenerate(cfg, envName, envData) }); err != nil {
 			return err
 		}
 	}
@@ -66,9 +69,9 @@ func RunBeforeGenerate(cfg *config.NopeusConfig, envName string, envData *config
 }
 
 // run all after generate functions for all plugins
-func RunAfterGenerate(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+func RunAfterGenerate(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
 	for _, plugin := range PluginManagerInstance.plugins {
-		if err := plugin.RunAfterGenerate(cfg, envName, envData); err != nil {
+		if err := runHook(ctx, plugin, "after-generate", func() error { return plugin.RunAfterGenerate(cfg, envName, envData) }); err != nil {
 			return err
 		}
 	}
@@ -76,9 +79,9 @@ func RunAfterGenerate(cfg *config.NopeusConfig, envName string, envData *config.
 }
 
 // run all before deploy functions for all plugins
-func RunBeforeDeploy(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+func RunBeforeDeploy(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
 	for _, plugin := range PluginManagerInstance.plugins {
-		if err := plugin.RunBeforeDeploy(cfg, envName, envData); err != nil {
+		if err := runHook(ctx, plugin, "before-deploy", func() error { return plugin.RunBeforeDeploy(cfg, envName, envData) }); err != nil {
 			return err
 		}
 	}
@@ -86,9 +89,9 @@ func RunBeforeDeploy(cfg *config.NopeusConfig, envName string, envData *config.E
 }
 
 // run all after deploy functions for all plugins
-func RunAfterDeploy(cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
+func RunAfterDeploy(ctx context.Context, cfg *config.NopeusConfig, envName string, envData *config.EnvironmentConfig) error {
 	for _, plugin := range PluginManagerInstance.plugins {
-		if err := plugin.RunAfterDeploy(cfg, envName, envData); err != nil {
+		if err := runHook(ctx, plugin, "after-deploy", func() error { return plugin.RunAfterDeploy(cfg, envName, envData) }); err != nil {
 			return err
 		}
 	}
@@ -96,11 +99,19 @@ func RunAfterDeploy(cfg *config.NopeusConfig, envName string, envData *config.En
 }
 
 // run all on finish functions for all plugins
-func RunOnFinish(cfg *config.NopeusConfig) error {
+func RunOnFinish(ctx context.Context, cfg *config.NopeusConfig) error {
 	for _, plugin := range PluginManagerInstance.plugins {
-		if err := plugin.RunOnFinish(cfg); err != nil {
+		if err := runHook(ctx, plugin, "on-finish", func() error { return plugin.RunOnFinish(cfg) }); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// run a single plugin hook in its own span
+func runHook(ctx context.Context, plugin Plugin, hook string, run func() error) error {
+	_, span := tracing.Start(ctx, "plugin "+plugin.Name()+" "+hook, tracing.Plugin(plugin.Name()))
+	err := run()
+	tracing.End(span, err)
+	return err
+}
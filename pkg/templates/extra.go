@@ -0,0 +1,90 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	tmpl "text/template"
+
+	"github.com/salfatigroup/nopeus/config"
+	"github.com/salfatigroup/nopeus/logger"
+)
+
+// match the output blocks in a terraform file
+var terraformOutputRegex = regexp.MustCompile(`(?m)^\s*output\s+"([^"]+)"`)
+
+// copy the user supplied terraform files next to the generated environment.
+// *.tf files are copied as is and *.tf.tmpl files are rendered with the
+// same values as the generated terraform modules.
+// returns the names of the outputs defined in the copied files
+func renderTerraformExtraDirs(cfg *config.NopeusConfig, destLocation string, envName string, envData *config.EnvironmentConfig) ([]string, error) {
+	basepath := filepath.Dir(cfg.Runtime.ConfigPath)
+	outputs := []string{}
+
+	for _, extraDir := range cfg.CAL.GetTerraform().GetExtraDirs() {
+		dir := filepath.Join(basepath, extraDir)
+		logger.Debugf("Copying extra terraform files from %s", dir)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read terraform extra dir %s: %w", extraDir, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			name := entry.Name()
+			if !strings.HasSuffix(name, ".tf") && !strings.HasSuffix(name, ".tf.tmpl") {
+				continue
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+
+			// render the templated terraform files
+			rendered := string(content)
+			if strings.HasSuffix(name, ".tmpl") {
+				if rendered, err = renderExtraTfTemplate(cfg, name, rendered, envName, envData); err != nil {
+					return nil, err
+				}
+				name = strings.TrimSuffix(name, ".tmpl")
+			}
+
+			// prefix the file name to avoid overriding the generated files
+			destFile := filepath.Join(destLocation, fmt.Sprintf("extra-%s-%s", filepath.Base(dir), name))
+			if err := writeFile(destFile, rendered); err != nil {
+				return nil, err
+			}
+
+			for _, match := range terraformOutputRegex.FindAllStringSubmatch(rendered, -1) {
+				outputs = append(outputs, match[1])
+			}
+		}
+	}
+
+	return outputs, nil
+}
+
+// render a user supplied terraform template
+func renderExtraTfTemplate(cfg *config.NopeusConfig, name string, content string, envName string, envData *config.EnvironmentConfig) (string, error) {
+	tmpl, err := tmpl.New(name).
+		Funcs(GetTempalteFuncs()).
+		Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	var renderedBuffer bytes.Buffer
+	if err := tmpl.Execute(&renderedBuffer, getTFValues(envName, envData, cfg)); err != nil {
+		return "", err
+	}
+
+	return renderedBuffer.String(), nil
+}
@@ -0,0 +1,105 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/salfatigroup/nopeus/config"
+)
+
+// write the files relative to the directory
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// return a config with the extra dirs relative to a temporary nopeus config
+func newExtraDirsConfig(t *testing.T, extraDirs ...string) *config.NopeusConfig {
+	runtime := config.NewRuntimeConfig()
+	runtime.ConfigPath = filepath.Join(t.TempDir(), "nopeus.yaml")
+
+	return &config.NopeusConfig{
+		Runtime: runtime,
+		CAL: &config.CloudApplicationLayerConfig{
+			Name:      "shop",
+			Terraform: &config.TerraformConfig{ExtraDirs: extraDirs},
+		},
+	}
+}
+
+// TestRenderTerraformExtraDirs copies the terraform files and renders the templates
+func TestRenderTerraformExtraDirs(t *testing.T) {
+	cfg := newExtraDirsConfig(t, "infra")
+	writeTestFiles(t, filepath.Join(filepath.Dir(cfg.Runtime.ConfigPath), "infra"), map[string]string{
+		"ses.tf":           "resource \"aws_ses_domain_identity\" \"main\" {}\n\noutput \"ses_domain\" {\n  value = \"${var.domain}\"\n}\n",
+		"queue.tf.tmpl":    "resource \"aws_sqs_queue\" \"jobs\" {\n  name = \"{{ .Name }}-{{ .Environment }}-jobs\"\n}\n\n  output \"queue-url\" {\n  value = aws_sqs_queue.jobs.url\n}\n",
+		"README.md":        "output \"ignored\" {}\n",
+		"modules/ses.tf":   "output \"nested\" {}\n",
+		"terraform.tfvars": "domain = \"example.com\"\n",
+	})
+	dest := t.TempDir()
+
+	outputs, err := renderTerraformExtraDirs(cfg, dest, "prod", &config.EnvironmentConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(outputs, []string{"queue-url", "ses_domain"}) {
+		t.Errorf("expected the outputs of the copied files, got %v", outputs)
+	}
+
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"extra-infra-queue.tf", "extra-infra-ses.tf"}) {
+		t.Errorf("expected only the prefixed terraform files, got %v", names)
+	}
+
+	// the plain files are copied as is, including terraform interpolations
+	ses, err := os.ReadFile(filepath.Join(dest, "extra-infra-ses.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ses) != "resource \"aws_ses_domain_identity\" \"main\" {}\n\noutput \"ses_domain\" {\n  value = \"${var.domain}\"\n}\n" {
+		t.Errorf("expected the terraform file to be copied as is, got:\n%s", ses)
+	}
+
+	queue, err := os.ReadFile(filepath.Join(dest, "extra-infra-queue.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(queue) != "resource \"aws_sqs_queue\" \"jobs\" {\n  name = \"shop-prod-jobs\"\n}\n\n  output \"queue-url\" {\n  value = aws_sqs_queue.jobs.url\n}\n" {
+		t.Errorf("expected the template to be rendered with the environment values, got:\n%s", queue)
+	}
+}
+
+// TestRenderTerraformExtraDirsErrors reports missing dirs and invalid templates
+func TestRenderTerraformExtraDirsErrors(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"missing dir":      nil,
+		"invalid template": {"main.tf.tmpl": "name = \"{{ .Name\"\n"},
+		"unknown value":    {"main.tf.tmpl": "name = \"{{ .Region.Name }}\"\n"},
+	} {
+		cfg := newExtraDirsConfig(t, "infra")
+		if files != nil {
+			writeTestFiles(t, filepath.Join(filepath.Dir(cfg.Runtime.ConfigPath), "infra"), files)
+		}
+
+		if _, err := renderTerraformExtraDirs(cfg, t.TempDir(), "prod", &config.EnvironmentConfig{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -13,11 +13,14 @@ type TerraformRendererValues struct {
     Environment string
     // deployment name
     Name string
+    // the terraform backend to render into the module
+    Backend *config.TerraformBackend
 }
 
 func getTFValues(envName string, envData *config.EnvironmentConfig, cfg *config.NopeusConfig) *TerraformRendererValues {
     return &TerraformRendererValues{
         Environment: envName,
         Name: cfg.CAL.GetName(),
+        Backend: cfg.CAL.GetTerraform().GetBackend(),
     }
 }
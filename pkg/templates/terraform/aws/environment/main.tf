@@ -5,6 +5,11 @@ terraform {
       version = "~> 4.20.1"
     }
   }
+{{- if .Backend }}
+
+  # partial backend configuration - the values are passed on terraform init
+  backend "{{ .Backend.Type }}" {}
+{{- end }}
 }
 
 provider "aws" {
@@ -31,6 +31,13 @@ func GenerateTerraformEnvironment(cfg *config.NopeusConfig, envName string, envD
 		return err
 	}
 
+	// copy the user supplied terraform files next to the generated modules
+	extraOutputs, err := renderTerraformExtraDirs(cfg, destLocation, envName, envData)
+	if err != nil {
+		return err
+	}
+	envData.SetExtraOutputs(extraOutputs)
+
 	return nil
 }
 
@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the name of the nopeus tracer and service
+const tracerName = "nopeus"
+
+// define where the spans are exported to
+type Options struct {
+	// the otlp http endpoint, e.g., http://localhost:4318
+	// defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable
+	Endpoint string
+
+	// additional headers sent to the otlp endpoint, e.g., api keys
+	Headers map[string]string
+
+	// a local file the spans are written to as json lines
+	File string
+}
+
+var (
+	provider *sdktrace.TracerProvider
+	files    []*os.File
+	mu       sync.Mutex
+)
+
+// returns true if an otlp endpoint is defined in the environment
+func hasOTLPEnvironment() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// initialize the global tracer provider. tracing stays disabled
+// unless an endpoint or a file is provided
+func Init(ctx context.Context, options Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	tpOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", tracerName),
+		)),
+	}
+
+	// export the spans to the otlp endpoint
+	if options.Endpoint != "" || hasOTLPEnvironment() {
+		exporter, err := newOTLPExporter(ctx, options)
+		if err != nil {
+			return err
+		}
+		tpOptions = append(tpOptions, sdktrace.WithBatcher(exporter))
+	}
+
+	// write the spans to a local file
+	if options.File != "" {
+		file, err := os.OpenFile(options.File, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return err
+		}
+		files = append(files, file)
+		tpOptions = append(tpOptions, sdktrace.WithBatcher(exporter))
+	}
+
+	// nothing to export to
+	if len(tpOptions) == 1 {
+		return nil
+	}
+
+	provider = sdktrace.NewTracerProvider(tpOptions...)
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+// create a new otlp http exporter
+func newOTLPExporter(ctx context.Context, options Options) (sdktrace.SpanExporter, error) {
+	exporterOptions := []otlptracehttp.Option{}
+
+	if options.Endpoint != "" {
+		endpoint, err := url.Parse(options.Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid tracing endpoint %s - expected a url, e.g., http://localhost:4318", options.Endpoint)
+		}
+
+		exporterOptions = append(exporterOptions, otlptracehttp.WithEndpoint(endpoint.Host))
+		if endpoint.Scheme == "http" {
+			exporterOptions = append(exporterOptions, otlptracehttp.WithInsecure())
+		}
+		if path := strings.TrimSuffix(endpoint.Path, "/"); path != "" {
+			exporterOptions = append(exporterOptions, otlptracehttp.WithURLPath(path))
+		}
+	}
+
+	if len(options.Headers) > 0 {
+		exporterOptions = append(exporterOptions, otlptracehttp.WithHeaders(options.Headers))
+	}
+
+	return otlptracehttp.New(ctx, exporterOptions...)
+}
+
+// flush the pending spans and stop the exporters
+// must be called before the process exits
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var err error
+	if provider != nil {
+		err = provider.Shutdown(ctx)
+		provider = nil
+	}
+
+	for _, file := range files {
+		file.Close()
+	}
+	files = nil
+
+	return err
+}
+
+// start a new span as a child of the span in the given context
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end the span and record the error if one occurred
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// the environment attribute of a span
+func Environment(name string) attribute.KeyValue {
+	return attribute.String("nopeus.environment", name)
+}
+
+// the service attribute of a span
+func Service(name string) attribute.KeyValue {
+	return attribute.String("nopeus.service", name)
+}
+
+// the plugin attribute of a span
+func Plugin(name string) attribute.KeyValue {
+	return attribute.String("nopeus.plugin", name)
+}
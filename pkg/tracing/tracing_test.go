@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// an in-process otlp collector that records the exported span names
+type collector struct {
+	mu    sync.Mutex
+	spans map[string]bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := &collectortrace.ExportTraceServiceRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, resourceSpans := range req.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, span := range scopeSpans.Spans {
+				c.spans[span.Name] = true
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	resp, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(resp)
+}
+
+// create a parent and a failed child span
+func emitSpans() {
+	ctx, parent := Start(context.Background(), "environment", Environment("prod"))
+	_, child := Start(ctx, "terraform init", Environment("prod"))
+	End(child, errors.New("init failed"))
+	End(parent, nil)
+}
+
+// TestExportToCollector exports the spans to an otlp http endpoint
+func TestExportToCollector(t *testing.T) {
+	c := &collector{spans: map[string]bool{}}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	if err := Init(context.Background(), Options{Endpoint: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	emitSpans()
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"environment", "terraform init"} {
+		if !c.spans[name] {
+			t.Errorf("expected span %s to be exported, got %v", name, c.spans)
+		}
+	}
+}
+
+// TestExportToFile writes the spans to a local json file
+func TestExportToFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.json")
+	if err := Init(context.Background(), Options{File: file}); err != nil {
+		t.Fatal(err)
+	}
+	emitSpans()
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	names := map[string]bool{}
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	for decoder.More() {
+		var span struct {
+			Name string `json:"Name"`
+		}
+		if err := decoder.Decode(&span); err != nil {
+			t.Fatal(err)
+		}
+		names[span.Name] = true
+	}
+
+	if !names["environment"] || !names["terraform init"] {
+		t.Errorf("expected the spans in the trace file, got %v", names)
+	}
+}